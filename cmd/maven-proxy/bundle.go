@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"maven-proxy/pkg/bundle"
+	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/index"
+	"maven-proxy/pkg/repository"
+)
+
+// runExport 将仓库或某个 groupId 下的文件导出为归档
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := flags.String("c", "config.yaml", "配置文件路径")
+	repoId := flags.String("repo", "", "导出的仓库")
+	groupId := flags.String("group", "", "只导出指定 groupId 下的文件")
+	format := flags.String("format", bundle.FormatTarGz, "归档格式：tar.gz 或 zip")
+	output := flags.String("o", "", "输出文件路径")
+	flags.Parse(args)
+
+	if *repoId == "" || *output == "" {
+		return fmt.Errorf("usage: maven-proxy export -repo <id> -o <file> [-group <groupId>] [-format tar.gz|zip]")
+	}
+
+	repo, err := openRepository(*configPath, *repoId)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	manifest, err := bundle.Export(repo, bundle.GroupRoot(*groupId), *format, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	log.Printf("exported %d files from %s to %s", len(manifest.Files), *repoId, *output)
+	return nil
+}
+
+// runImport 将归档导入 hosted 仓库
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := flags.String("c", "config.yaml", "配置文件路径")
+	repoId := flags.String("repo", "", "导入的目标仓库")
+	conflict := flags.String("conflict", string(bundle.PolicyFail), "已存在同名文件时的处理方式：skip、overwrite 或 fail")
+	flags.Parse(args)
+
+	if *repoId == "" || flags.NArg() != 1 {
+		return fmt.Errorf("usage: maven-proxy import -repo <id> [-conflict skip|overwrite|fail] <bundle>")
+	}
+	policy, err := bundle.ParsePolicy(*conflict)
+	if err != nil {
+		return err
+	}
+
+	cfg, repoCfg, err := loadRepositoryConfig(*configPath, *repoId)
+	if err != nil {
+		return err
+	}
+	if repoCfg.Type != "hosted" && repoCfg.Type != "" {
+		return fmt.Errorf("repository %s is not a hosted repository", *repoId)
+	}
+	base, err := newStorageSet(cfg).forRepository(repoCfg)
+	if err != nil {
+		return err
+	}
+
+	// 导入的文件需要计入配额并写入索引，使用与服务端相同的存储包装器
+	var idx *index.Index
+	if cfg.Index != nil {
+		if idx, err = index.Open(cfg.Index.Path); err != nil {
+			return fmt.Errorf("%w; stop the server or import through POST /_admin/import/%s", err, *repoId)
+		}
+		defer idx.Close()
+	}
+	repoStorage, err := newRepositoryStorage(nil, base, idx, repoCfg)
+	if err != nil {
+		return err
+	}
+	repo := newHostedRepository(repoCfg, repoStorage)
+
+	result, err := bundle.Import(repo, flags.Arg(0), policy)
+	if result != nil {
+		log.Printf("imported %d files, overwritten %d, skipped %d, conflicts %d",
+			result.Imported, result.Overwritten, result.Skipped, len(result.Conflicts))
+		for _, p := range result.Conflicts {
+			log.Printf("conflict: %s", p)
+		}
+	}
+	return err
+}
+
+// openRepository 按配置创建单个 hosted 或 proxy 仓库，只用于导出等只读的离线命令
+func openRepository(configPath string, repoId string) (repository.Repository, error) {
+	cfg, repoCfg, err := loadRepositoryConfig(configPath, repoId)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newStorageSet(cfg).forRepository(repoCfg)
+	if err != nil {
+		return nil, err
+	}
+	repoStorage, _, err := openRepositoryStorage(base, repoCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch repoCfg.Type {
+	case "hosted", "":
+		return newHostedRepository(repoCfg, repoStorage), nil
+	case "proxy":
+		return newProxyRepository(repoCfg, repoStorage), nil
+	default:
+		return nil, fmt.Errorf("repository %s of type %s has no storage", repoId, repoCfg.Type)
+	}
+}
+
+// loadRepositoryConfig 加载配置并查找仓库
+func loadRepositoryConfig(configPath string, repoId string) (*config.Config, *config.Repository, error) {
+	cfg, err := config.NewFileLoader(configPath).Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, repoCfg := range cfg.Repository {
+		if repoCfg.Id == repoId {
+			return cfg, repoCfg, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("repository %s not found", repoId)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/index"
+)
+
+// runIndex 执行索引维护命令，目前支持 rebuild
+//
+// 索引文件同一时间只能被一个进程打开，服务运行时请使用 POST /_admin/index/rebuild
+func runIndex(args []string) error {
+	if len(args) == 0 || args[0] != "rebuild" {
+		return fmt.Errorf("usage: maven-proxy index rebuild [-c config.yaml] [-repo id]")
+	}
+
+	flags := flag.NewFlagSet("index rebuild", flag.ExitOnError)
+	configPath := flags.String("c", "config.yaml", "配置文件路径")
+	repo := flags.String("repo", "", "只重建指定仓库的索引")
+	flags.Parse(args[1:])
+
+	cfg, err := config.NewFileLoader(*configPath).Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Index == nil {
+		return fmt.Errorf("index is not configured")
+	}
+
+	storages := newStorageSet(cfg)
+	idx, err := index.Open(cfg.Index.Path)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	for _, repoCfg := range cfg.Repository {
+		if repoCfg.Type == "group" || (*repo != "" && repoCfg.Id != *repo) {
+			continue
+		}
+
+		base, err := storages.forRepository(repoCfg)
+		if err != nil {
+			return err
+		}
+		repoStorage, _, err := openRepositoryStorage(base, repoCfg)
+		if err != nil {
+			return fmt.Errorf("open repository %s failed: %w", repoCfg.Id, err)
+		}
+		count, err := idx.Rebuild(repoCfg.Id, repoStorage)
+		if err != nil {
+			return fmt.Errorf("rebuild index of %s failed: %w", repoCfg.Id, err)
+		}
+		log.Printf("rebuilt index of %s: %d files", repoCfg.Id, count)
+	}
+	return nil
+}
@@ -3,15 +3,44 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"maven-proxy/internal/server"
 	"maven-proxy/pkg/auth"
 	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/event"
+	"maven-proxy/pkg/index"
 	"maven-proxy/pkg/repository"
 	"maven-proxy/pkg/storage"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				log.Fatalf("migrate failed: %v", err)
+			}
+			return
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				log.Fatalf("export failed: %v", err)
+			}
+			return
+		case "import":
+			if err := runImport(os.Args[2:]); err != nil {
+				log.Fatalf("import failed: %v", err)
+			}
+			return
+		case "index":
+			if err := runIndex(os.Args[2:]); err != nil {
+				log.Fatalf("index failed: %v", err)
+			}
+			return
+		}
+	}
+
 	// 加载配置
 	loader := config.NewLoader()
 	cfg, err := loader.Load()
@@ -19,8 +48,12 @@ func main() {
 		log.Fatalf("load config failed: %v", err)
 	}
 
-	// 初始化存储层
-	baseStorage := storage.NewFileSystemStorage(cfg.LocalRepository)
+	// 初始化存储层，仓库可以配置独立的存储后端
+	storages := newStorageSet(cfg)
+	if _, err := storages.open(cfg.Storage); err != nil {
+		log.Fatalf("init storage failed: %v", err)
+	}
+	log.Printf("storage backend: %s", cfg.Storage.Type)
 
 	// 创建认证器
 	authenticator := auth.NewBasicAuthenticator(cfg.User)
@@ -28,6 +61,26 @@ func main() {
 	// 创建服务器
 	srv := server.NewServer(cfg, authenticator)
 
+	// 打开文件索引
+	var idx *index.Index
+	if cfg.Index != nil {
+		if idx, err = index.Open(cfg.Index.Path); err != nil {
+			log.Fatalf("open index failed: %v", err)
+		}
+		defer idx.Close()
+		srv.SetIndex(idx)
+		log.Printf("index enabled: %s", cfg.Index.Path)
+	}
+
+	// 创建完整性校验器
+	scrubber := newScrubber(cfg.Scrub)
+	if scrubber != nil {
+		srv.SetScrubber(scrubber)
+	}
+
+	// 创建目录监视器
+	watcher := newWatcher(cfg, srv)
+
 	// 初始化仓库
 	repoStore := make(map[string]repository.Repository)
 
@@ -40,26 +93,42 @@ func main() {
 		switch repoCfg.Type {
 		case "hosted", "":
 			// 创建 hosted 仓库
-			repoStorage := storage.NewPrefixedStorage(baseStorage, repoCfg.Target)
-			repo := repository.NewHostedRepository(
-				repoCfg.Id,
-				repoCfg.Mode,
-				repoStorage,
-			)
+			base, err := storages.forRepository(repoCfg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			repoStorage, err := newRepositoryStorage(srv, base, idx, repoCfg)
+			if err != nil {
+				log.Fatalf("init storage of repository %s failed: %v", repoCfg.Id, err)
+			}
+			repo := newHostedRepository(repoCfg, repoStorage)
 			repoStore[repoCfg.Id] = repo
+			if scrubber != nil {
+				scrubber.Add(repoCfg.Id, repoStorage, repo.Delete)
+			}
+			if watcher != nil {
+				addWatchDir(watcher, cfg, repoCfg, repoStorage)
+			}
 			log.Printf("initialized hosted repository: %s", repoCfg.Id)
 
 		case "proxy":
 			// 创建 proxy 仓库
-			repoStorage := storage.NewPrefixedStorage(baseStorage, repoCfg.Target)
-			repo := repository.NewProxyRepository(
-				repoCfg.Id,
-				repoCfg.Mode,
-				repoCfg.Cache,
-				repoCfg.Mirror,
-				repoStorage,
-			)
+			base, err := storages.forRepository(repoCfg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			repoStorage, err := newRepositoryStorage(srv, base, idx, repoCfg)
+			if err != nil {
+				log.Fatalf("init storage of repository %s failed: %v", repoCfg.Id, err)
+			}
+			repo := newProxyRepository(repoCfg, repoStorage)
 			repoStore[repoCfg.Id] = repo
+			if notFound := repo.NotFoundCache(); notFound != nil {
+				srv.RegisterNotFoundCache(repoCfg.Id, notFound)
+			}
+			if scrubber != nil {
+				scrubber.Add(repoCfg.Id, repoStorage, nil)
+			}
 			log.Printf("initialized proxy repository: %s", repoCfg.Id)
 		}
 	}
@@ -102,6 +171,34 @@ func main() {
 		srv.RegisterRepository(id, repo)
 	}
 
+	// 分片存储在后台将新增卷之后位置不正确的文件移到所属的卷
+	for _, sharded := range storages.sharded() {
+		srv.RegisterShardedStorage(sharded)
+		sharded.Start()
+	}
+
+	if scrubber != nil {
+		scrubber.Start()
+		log.Printf("scrub enabled, interval %s", cfg.Scrub.Interval)
+	}
+
+	if watcher != nil {
+		// 直接放入目录的文件同样需要更新元数据
+		srv.Events().Subscribe(func(e event.Event) {
+			if e.Source != event.SourceWatcher {
+				return
+			}
+			if hosted, ok := repoStore[e.Repository].(*repository.HostedRepository); ok {
+				if err := hosted.RefreshMetadata(e.Path); err != nil {
+					log.Printf("warning: update metadata of %s%s failed: %v", e.Repository, e.Path, err)
+				}
+			}
+		})
+
+		watcher.Start()
+		log.Printf("watch enabled, interval %s", cfg.Watch.Interval)
+	}
+
 	// 启动服务器
 	addr := cfg.Listen + ":" + cfg.Port
 	log.Printf("maven-proxy server starting on %s", addr)
@@ -111,3 +208,25 @@ func main() {
 		log.Fatalf("server failed to start: %v", err)
 	}
 }
+
+// newHostedRepository 按配置创建 hosted 仓库
+func newHostedRepository(repoCfg *config.Repository, repoStorage storage.Storage) *repository.HostedRepository {
+	repo := repository.NewHostedRepository(repoCfg.Id, repoCfg.Mode, repoStorage)
+	if repoCfg.GenerateMetadata == nil || *repoCfg.GenerateMetadata {
+		repo.EnableMetadata()
+	}
+	return repo
+}
+
+// newProxyRepository 按配置创建 proxy 仓库
+func newProxyRepository(repoCfg *config.Repository, repoStorage storage.Storage) *repository.ProxyRepository {
+	repo := repository.NewProxyRepository(repoCfg.Id, repoCfg.Mode, repoCfg.Cache, repoCfg.Mirror, repoStorage)
+	if repoCfg.MetadataCache != nil {
+		repo.EnableMetadataCache(repoCfg.MetadataCache.TTL)
+	}
+	if c := repoCfg.NotFoundCache; c != nil {
+		excludeMetadata := c.ExcludeMetadata == nil || *c.ExcludeMetadata
+		repo.SetNotFoundCache(repository.NewNotFoundCache(c.TTL, c.MaxEntries, excludeMetadata))
+	}
+	return repo
+}
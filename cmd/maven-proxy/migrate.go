@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/index"
+	"maven-proxy/pkg/storage"
+)
+
+// migrateOptions migrate 子命令参数
+type migrateOptions struct {
+	source    string
+	target    string
+	repos     string
+	workers   int
+	dryRun    bool
+	stateFile string
+}
+
+// migrateStats 单个仓库的迁移统计
+type migrateStats struct {
+	files   int64
+	bytes   int64
+	copied  int64
+	skipped int64
+	failed  int64
+}
+
+// migrateJob 单个文件的复制任务
+type migrateJob struct {
+	repo  string
+	path  string
+	dstID string // 目标存储的指纹
+	src   storage.Storage
+	dst   storage.Storage
+	stats *migrateStats
+}
+
+// runMigrate 将源配置中每个仓库的文件复制到目标配置的存储后端
+//
+// 已完成的文件连同目标存储的指纹记录在状态文件中，中断后向同一目标重新执行会跳过这些文件。
+// 目标按服务端相同的方式叠加配额和索引，每个文件复制后从目标存储读回并比对 SHA-1
+func runMigrate(args []string) error {
+	opts := &migrateOptions{}
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.StringVar(&opts.source, "c", "config.yaml", "源配置文件路径")
+	flags.StringVar(&opts.target, "to", "", "目标配置文件路径，使用其中的 storage 配置")
+	flags.StringVar(&opts.repos, "repo", "", "只迁移指定的仓库，多个用逗号分隔")
+	flags.IntVar(&opts.workers, "workers", 4, "并行复制的文件数")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "只统计需要迁移的文件，不实际复制")
+	flags.StringVar(&opts.stateFile, "state", "migrate.state", "记录已完成文件的状态文件，用于中断后继续")
+	flags.Parse(args)
+
+	if opts.target == "" {
+		return fmt.Errorf("destination config is required, use -to")
+	}
+	if opts.workers < 1 {
+		opts.workers = 1
+	}
+
+	srcCfg, err := config.NewFileLoader(opts.source).Load()
+	if err != nil {
+		return fmt.Errorf("load source config failed: %w", err)
+	}
+	dstCfg, err := config.NewFileLoader(opts.target).Load()
+	if err != nil {
+		return fmt.Errorf("load destination config failed: %w", err)
+	}
+
+	srcStorages := newStorageSet(srcCfg)
+	dstStorages := newStorageSet(dstCfg)
+
+	done, err := loadMigrateState(opts.stateFile)
+	if err != nil {
+		return fmt.Errorf("load state file failed: %w", err)
+	}
+	var state *os.File
+	if !opts.dryRun {
+		if state, err = os.OpenFile(opts.stateFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return fmt.Errorf("open state file failed: %w", err)
+		}
+		defer state.Close()
+	}
+
+	selected := map[string]bool{}
+	for _, id := range strings.Split(opts.repos, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			selected[id] = true
+		}
+	}
+
+	// 目标配置中没有的仓库沿用源配置，存储使用目标的全局存储
+	dstRepos := make(map[string]*config.Repository)
+	for _, repoCfg := range dstCfg.Repository {
+		dstRepos[repoCfg.Id] = repoCfg
+	}
+
+	// 复制到目标的文件需要计入配额并写入索引，目标配置了索引时打开
+	var idx *index.Index
+	if dstCfg.Index != nil && !opts.dryRun {
+		if idx, err = index.Open(dstCfg.Index.Path); err != nil {
+			return fmt.Errorf("open destination index failed: %w", err)
+		}
+		defer idx.Close()
+	}
+
+	jobs := make(chan migrateJob)
+	var stateMu sync.Mutex
+	var stateErr error
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				size, err := migrateFile(job.src, job.dst, job.path)
+				if err != nil {
+					atomic.AddInt64(&job.stats.failed, 1)
+					log.Printf("migrate %s%s failed: %v", job.repo, job.path, err)
+					continue
+				}
+				atomic.AddInt64(&job.stats.copied, 1)
+				atomic.AddInt64(&job.stats.bytes, size)
+
+				stateMu.Lock()
+				if _, err := fmt.Fprintf(state, "%s\t%s\t%s\n", job.dstID, job.repo, job.path); err != nil && stateErr == nil {
+					stateErr = err
+				}
+				stateMu.Unlock()
+			}
+		}()
+	}
+
+	results := []*migrateStats{}
+	ids := []string{}
+	var walkErr error
+	for _, srcRepo := range srcCfg.Repository {
+		if srcRepo.Type == "group" || (len(selected) > 0 && !selected[srcRepo.Id]) {
+			continue
+		}
+		dstRepo, ok := dstRepos[srcRepo.Id]
+		if !ok {
+			inherited := *srcRepo
+			inherited.Storage = nil
+			dstRepo = &inherited
+		}
+		if reflect.DeepEqual(repositoryStorageConfig(srcCfg, srcRepo), repositoryStorageConfig(dstCfg, dstRepo)) &&
+			srcRepo.Target == dstRepo.Target {
+			walkErr = fmt.Errorf("repository %s: source and destination are the same", srcRepo.Id)
+			break
+		}
+
+		srcBase, err := srcStorages.forRepository(srcRepo)
+		if err != nil {
+			walkErr = err
+			break
+		}
+		dstBase, err := dstStorages.forRepository(dstRepo)
+		if err != nil {
+			walkErr = err
+			break
+		}
+		src, _, err := openRepositoryStorage(srcBase, srcRepo)
+		if err != nil {
+			walkErr = fmt.Errorf("open source repository %s failed: %w", srcRepo.Id, err)
+			break
+		}
+		var dst storage.Storage
+		if opts.dryRun {
+			dst, _, err = openRepositoryStorage(dstBase, dstRepo)
+		} else {
+			dst, err = newRepositoryStorage(nil, dstBase, idx, dstRepo)
+		}
+		if err != nil {
+			walkErr = fmt.Errorf("open destination repository %s failed: %w", dstRepo.Id, err)
+			break
+		}
+		dstID, err := destinationFingerprint(dstCfg, dstRepo)
+		if err != nil {
+			walkErr = err
+			break
+		}
+
+		stats := &migrateStats{}
+		results = append(results, stats)
+		ids = append(ids, srcRepo.Id)
+		log.Printf("migrating repository %s", srcRepo.Id)
+
+		err = storage.Walk(src, "/", func(p string, info storage.FileInfo) error {
+			stats.files++
+			if done[dstID+"\t"+srcRepo.Id+"\t"+p] {
+				stats.skipped++
+				return nil
+			}
+			if opts.dryRun {
+				stats.copied++
+				stats.bytes += info.Size
+				return nil
+			}
+			jobs <- migrateJob{repo: srcRepo.Id, path: p, dstID: dstID, src: src, dst: dst, stats: stats}
+			return nil
+		})
+		if err != nil && src.Exists("/") {
+			walkErr = fmt.Errorf("walk repository %s failed: %w", srcRepo.Id, err)
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 输出报告
+	action := "copied"
+	if opts.dryRun {
+		action = "to copy"
+	}
+	failed := int64(0)
+	for i, stats := range results {
+		log.Printf("repository %s: %d files, %d %s (%d bytes), %d already migrated, %d failed",
+			ids[i], stats.files, stats.copied, action, stats.bytes, stats.skipped, stats.failed)
+		failed += stats.failed
+	}
+
+	if walkErr != nil {
+		return walkErr
+	}
+	if stateErr != nil {
+		return fmt.Errorf("write state file failed, completed files may be copied again: %w", stateErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to migrate, run again to retry", failed)
+	}
+	return nil
+}
+
+// migrateFile 复制单个文件并从目标读回校验，返回复制的字节数
+func migrateFile(src, dst storage.Storage, p string) (int64, error) {
+	content, err := src.Read(p)
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
+
+	hasher := sha1.New()
+	counter := &countWriter{}
+	if err := dst.Write(p, io.TeeReader(content, io.MultiWriter(hasher, counter)), content.Size); err != nil {
+		return 0, err
+	}
+	expected := hasher.Sum(nil)
+
+	copied, err := dst.Read(p)
+	if err != nil {
+		return 0, fmt.Errorf("read back failed: %w", err)
+	}
+	defer copied.Close()
+
+	verifier := sha1.New()
+	if _, err := io.Copy(verifier, copied); err != nil {
+		return 0, fmt.Errorf("read back failed: %w", err)
+	}
+	if actual := verifier.Sum(nil); !bytes.Equal(actual, expected) {
+		return 0, fmt.Errorf("checksum mismatch: source %x, destination %x", expected, actual)
+	}
+	return counter.n, nil
+}
+
+// loadMigrateState 读取状态文件中已完成的文件，每行为 <目标指纹>\t<仓库 ID>\t<路径>，
+// 换了目标存储后指纹不同，所有文件重新复制
+func loadMigrateState(stateFile string) (map[string]bool, error) {
+	done := make(map[string]bool)
+
+	file, err := os.Open(stateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return done, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.Count(line, "\t") == 2 {
+			done[line] = true
+		}
+	}
+	return done, scanner.Err()
+}
+
+// destinationFingerprint 根据目标仓库的存储、目录、分层和加密配置计算指纹
+func destinationFingerprint(cfg *config.Config, repoCfg *config.Repository) (string, error) {
+	data, err := json.Marshal(struct {
+		Storage    *config.Storage
+		Target     string
+		Tiered     *config.Tiered
+		Encryption *config.Encryption
+	}{repositoryStorageConfig(cfg, repoCfg), repoCfg.Target, repoCfg.Tiered, repoCfg.Encryption})
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// countWriter 统计写入的字节数
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
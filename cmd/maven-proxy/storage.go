@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"maven-proxy/internal/scrub"
+	"maven-proxy/internal/server"
+	"maven-proxy/internal/watch"
+	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/index"
+	"maven-proxy/pkg/storage"
+)
+
+// sharedTempMaxAge 共享目录时清理临时文件的最小闲置时间
+const sharedTempMaxAge = time.Hour
+
+// newStorage 根据配置创建存储后端
+func newStorage(cfg *config.Storage) (storage.Storage, error) {
+	switch cfg.Type {
+	case "filesystem", "":
+		fs := newFileSystemStorage(cfg.Path, cfg.Lock)
+		cleanupTempFiles(fs, cfg.Lock)
+		return fs, nil
+
+	case "sharded":
+		// 按路径分布到多个卷
+		sharded, err := storage.NewShardedStorage(cfg.Volumes)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Lock != nil {
+			sharded.EnableFileLocks(storage.LockOptions{TTL: cfg.Lock.TTL, Timeout: cfg.Lock.Timeout})
+		}
+		cleanupTempFiles(sharded, cfg.Lock)
+		return sharded, nil
+
+	case "blob":
+		// 内容寻址存储，跨仓库去重
+		return storage.NewBlobStorage(cfg.Path)
+
+	case "s3":
+		if cfg.S3 == nil {
+			return nil, fmt.Errorf("storage type s3 requires an s3 section")
+		}
+
+		accessKey := cfg.S3.AccessKey
+		if accessKey == "" {
+			accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		secretKey := cfg.S3.SecretKey
+		if secretKey == "" {
+			secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+
+		return storage.NewS3Storage(storage.S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			Prefix:    cfg.S3.Prefix,
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			PathStyle: cfg.S3.PathStyle,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
+	}
+}
+
+// storageSet 按配置打开存储后端，配置相同的仓库共用同一个实例
+type storageSet struct {
+	cfg    *config.Config
+	opened []openedStorage
+}
+
+type openedStorage struct {
+	cfg     *config.Storage
+	storage storage.Storage
+}
+
+func newStorageSet(cfg *config.Config) *storageSet {
+	return &storageSet{cfg: cfg}
+}
+
+// open 打开存储后端，已经打开过相同配置时直接返回
+func (s *storageSet) open(cfg *config.Storage) (storage.Storage, error) {
+	for _, opened := range s.opened {
+		if reflect.DeepEqual(opened.cfg, cfg) {
+			return opened.storage, nil
+		}
+	}
+
+	st, err := newStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.opened = append(s.opened, openedStorage{cfg: cfg, storage: st})
+	return st, nil
+}
+
+// forRepository 打开仓库使用的存储后端
+func (s *storageSet) forRepository(repoCfg *config.Repository) (storage.Storage, error) {
+	st, err := s.open(repositoryStorageConfig(s.cfg, repoCfg))
+	if err != nil {
+		return nil, fmt.Errorf("init storage of repository %s failed: %w", repoCfg.Id, err)
+	}
+	return st, nil
+}
+
+// sharded 返回已打开的分片存储
+func (s *storageSet) sharded() []*storage.ShardedStorage {
+	result := []*storage.ShardedStorage{}
+	for _, opened := range s.opened {
+		if sharded, ok := opened.storage.(*storage.ShardedStorage); ok {
+			result = append(result, sharded)
+		}
+	}
+	return result
+}
+
+// repositoryStorageConfig 返回仓库使用的存储配置，仓库没有独立配置时使用全局配置
+func repositoryStorageConfig(cfg *config.Config, repoCfg *config.Repository) *config.Storage {
+	if repoCfg.Storage != nil {
+		return repoCfg.Storage
+	}
+	return cfg.Storage
+}
+
+// newRepositoryStorage 创建仓库使用的存储，按配置叠加配额、内存缓存等包装器。
+// srv 为 nil 时用于离线命令，不注册统计信息，也不启动分层迁移和缓存淘汰等后台任务
+func newRepositoryStorage(srv *server.Server, base storage.Storage, idx *index.Index, repoCfg *config.Repository) (storage.Storage, error) {
+	repoStorage, tiered, err := openRepositoryStorage(base, repoCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if tiered != nil && srv != nil {
+		srv.RegisterTieredStorage(repoCfg.Id, tiered)
+		tiered.Start()
+		log.Printf("repository %s: tiered storage enabled, migrate to %s tier after %s",
+			repoCfg.Id, repoCfg.Tiered.Cold.Type, repoCfg.Tiered.MigrateAfter)
+	}
+	if repoCfg.Encryption != nil {
+		log.Printf("repository %s: encryption at rest enabled", repoCfg.Id)
+	}
+
+	if repoCfg.Quota != nil {
+		quota, err := storage.NewQuotaStorage(
+			repoStorage,
+			repoCfg.Id,
+			int64(repoCfg.Quota.MaxSize),
+			repoCfg.Quota.MaxFiles,
+			repoCfg.Quota.SoftLimit,
+		)
+		if err != nil {
+			return nil, err
+		}
+		usage := quota.Usage()
+		log.Printf("repository %s: quota enabled, using %d/%d bytes, %d/%d files",
+			repoCfg.Id, usage.Bytes, usage.MaxBytes, usage.Files, usage.MaxFiles)
+		repoStorage = quota
+	}
+
+	if repoCfg.MemoryCache != nil {
+		cache := storage.NewMemoryCacheStorage(
+			repoStorage,
+			int64(repoCfg.MemoryCache.MaxSize),
+			int64(repoCfg.MemoryCache.MaxFileSize),
+		)
+		if srv != nil {
+			srv.RegisterMemoryCache(repoCfg.Id, cache)
+		}
+		repoStorage = cache
+		log.Printf("repository %s: memory cache enabled, max size %d bytes", repoCfg.Id, repoCfg.MemoryCache.MaxSize)
+	}
+
+	// 索引记录明文的长度和摘要，放在最外层
+	if idx != nil {
+		indexed := index.NewIndexedStorage(repoStorage, idx, repoCfg.Id)
+		if srv != nil {
+			srv.RegisterIndexedStorage(repoCfg.Id, indexed)
+		}
+		repoStorage = indexed
+	}
+
+	// 淘汰通过索引包装器删除，保持索引同步
+	if repoCfg.CacheLimit != nil {
+		if repoCfg.Type != "proxy" {
+			log.Printf("warning: repository %s: cacheLimit only applies to proxy repositories, ignored", repoCfg.Id)
+		} else if repoCfg.CacheLimit.MaxSize > 0 {
+			evicting := storage.NewEvictingStorage(
+				repoStorage,
+				int64(repoCfg.CacheLimit.MaxSize),
+				repoCfg.CacheLimit.LowWatermark,
+				repoCfg.CacheLimit.Interval,
+			)
+			if srv != nil {
+				srv.RegisterEvictingStorage(repoCfg.Id, evicting)
+				evicting.Start()
+			}
+			repoStorage = evicting
+			log.Printf("repository %s: cache limited to %d bytes", repoCfg.Id, repoCfg.CacheLimit.MaxSize)
+		}
+	}
+
+	return repoStorage, nil
+}
+
+// openRepositoryStorage 创建仓库数据所在的存储，只包含决定数据存放位置和格式的前缀、分层和加密，
+// 不启动后台任务，迁移等离线操作直接使用
+func openRepositoryStorage(base storage.Storage, repoCfg *config.Repository) (storage.Storage, *storage.TieredStorage, error) {
+	var repoStorage storage.Storage = storage.NewPrefixedStorage(base, repoCfg.Target)
+
+	var tiered *storage.TieredStorage
+	if repoCfg.Tiered != nil {
+		var err error
+		if tiered, err = newTieredStorage(repoStorage, repoCfg); err != nil {
+			return nil, nil, fmt.Errorf("init tiered storage failed: %w", err)
+		}
+		repoStorage = tiered
+	}
+
+	if repoCfg.Encryption != nil {
+		keyring, err := loadKeyring(repoCfg.Encryption)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load encryption keys failed: %w", err)
+		}
+		repoStorage = storage.NewEncryptedStorage(repoStorage, keyring)
+	}
+
+	return repoStorage, tiered, nil
+}
+
+// newTieredStorage 以仓库存储为热层创建分层存储，冷层按相同的 target 划分仓库
+func newTieredStorage(hot storage.Storage, repoCfg *config.Repository) (*storage.TieredStorage, error) {
+	cfg := repoCfg.Tiered
+	if cfg.Cold == nil {
+		return nil, fmt.Errorf("tiered storage requires a cold section")
+	}
+	if cfg.Cold.Type != "s3" && cfg.Cold.Path == "" {
+		return nil, fmt.Errorf("cold tier of type %s requires a path", cfg.Cold.Type)
+	}
+
+	cold, err := newStorage(cfg.Cold)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewTieredStorage(hot, storage.NewPrefixedStorage(cold, repoCfg.Target), storage.TieredOptions{
+		MigrateAfter: cfg.MigrateAfter,
+		Interval:     cfg.Interval,
+		Promote:      cfg.Promote == nil || *cfg.Promote,
+	}), nil
+}
+
+// newScrubber 根据配置创建完整性校验器，未配置时返回 nil
+func newScrubber(cfg *config.Scrub) *scrub.Scrubber {
+	if cfg == nil {
+		return nil
+	}
+
+	var quarantine storage.Storage
+	if cfg.Quarantine != "" {
+		quarantine = storage.NewFileSystemStorage(cfg.Quarantine)
+	}
+	return scrub.NewScrubber(cfg.Interval, quarantine)
+}
+
+// cleanupTempFiles 清理上次异常退出遗留的临时文件，共享目录时其他实例可能正在写入，只清理长时间没有修改的文件
+func cleanupTempFiles(s interface {
+	CleanupTempFiles(olderThan time.Duration) (int, error)
+}, lock *config.FileLock) {
+	olderThan := time.Duration(0)
+	if lock != nil {
+		olderThan = sharedTempMaxAge
+	}
+	if removed, err := s.CleanupTempFiles(olderThan); err != nil {
+		log.Printf("warning: cleanup temp files failed: %v", err)
+	} else if removed > 0 {
+		log.Printf("removed %d abandoned temp files", removed)
+	}
+}
+
+// newFileSystemStorage 创建文件系统存储，配置了锁文件时启用
+func newFileSystemStorage(path string, lock *config.FileLock) *storage.FileSystemStorage {
+	fs := storage.NewFileSystemStorage(path)
+	if lock != nil {
+		fs.EnableFileLocks(storage.LockOptions{TTL: lock.TTL, Timeout: lock.Timeout})
+	}
+	return fs
+}
+
+// newWatcher 根据配置创建目录监视器
+func newWatcher(cfg *config.Config, srv *server.Server) *watch.Watcher {
+	if cfg.Watch == nil {
+		return nil
+	}
+	return watch.NewWatcher(cfg.Watch.Interval, srv.Events())
+}
+
+// addWatchDir 监视 hosted 仓库目录，直接放入的文件通过仓库存储重新写入。
+// 只支持文件系统存储，加密仓库中直接放入的明文文件无法与已加密的文件区分，不监视
+func addWatchDir(watcher *watch.Watcher, cfg *config.Config, repoCfg *config.Repository, repoStorage storage.Storage) {
+	storageCfg := repositoryStorageConfig(cfg, repoCfg)
+	if storageCfg.Type != "filesystem" && storageCfg.Type != "" {
+		log.Printf("warning: watch requires filesystem storage, repository %s is not watched", repoCfg.Id)
+		return
+	}
+	if repoCfg.Encryption != nil {
+		return
+	}
+	dir := filepath.Join(storageCfg.Path, repoCfg.Target)
+	watcher.Add(repoCfg.Id, dir, repoStorage)
+}
+
+// loadKeyring 从密钥文件和环境变量加载加密密钥
+func loadKeyring(cfg *config.Encryption) (*storage.Keyring, error) {
+	text := ""
+	if cfg.KeyFile != "" {
+		data, err := ioutil.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		text += string(data) + "\n"
+	}
+	if cfg.KeyEnv != "" {
+		text += os.Getenv(cfg.KeyEnv)
+	}
+	return storage.ParseKeyring(text, cfg.ActiveKey)
+}
@@ -0,0 +1,388 @@
+// internal/scrub/scrub.go
+package scrub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"maven-proxy/internal/util"
+	"maven-proxy/pkg/storage"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetLevel(logrus.InfoLevel)
+	log.SetFormatter(&logrus.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+	})
+}
+
+// checksumTypes 校验和文件扩展名对应的算法，按校验优先级排列
+var checksumTypes = []string{"sha1", "md5", "sha256", "sha512"}
+
+// maxChecksumSize 校验和文件的最大长度，超过时视为损坏
+const maxChecksumSize = 1024
+
+// Issue 校验失败的文件
+type Issue struct {
+	Path        string `json:"path"`
+	Algorithm   string `json:"algorithm,omitempty"`
+	Expected    string `json:"expected,omitempty"`
+	Actual      string `json:"actual,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Quarantined bool   `json:"quarantined"`
+}
+
+// Report 单个仓库一次校验的结果
+type Report struct {
+	Repository       string    `json:"repository"`
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	Files            int       `json:"files"`    // 检查的文件数，不含校验和文件
+	Verified         int       `json:"verified"` // 有校验和且全部匹配的文件数
+	Corrupt          []Issue   `json:"corrupt"`
+	MissingChecksums []string  `json:"missingChecksums"`
+	OrphanChecksums  []string  `json:"orphanChecksums"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// Status 校验器状态
+type Status struct {
+	Running  bool               `json:"running"`
+	Interval string             `json:"interval"`
+	LastRun  time.Time          `json:"lastRun"`
+	Reports  map[string]*Report `json:"reports"`
+}
+
+// target 需要校验的仓库
+type target struct {
+	storage storage.Storage
+	remove  func(path string) error // 删除隔离的构件，为 nil 时直接从存储删除
+}
+
+// Scrubber 定期遍历仓库存储，重新计算摘要并与 .sha1、.md5 等校验和文件比对
+type Scrubber struct {
+	interval   time.Duration
+	quarantine storage.Storage // 为 nil 时只报告不隔离
+
+	mu       sync.Mutex
+	targets  map[string]target
+	reports  map[string]*Report
+	running  bool
+	lastRun  time.Time
+	stopChan chan struct{}
+}
+
+// NewScrubber 创建校验器，quarantine 不为 nil 时损坏的文件会移到其中 <仓库 ID>/<路径> 下
+func NewScrubber(interval time.Duration, quarantine storage.Storage) *Scrubber {
+	return &Scrubber{
+		interval:   interval,
+		quarantine: quarantine,
+		targets:    make(map[string]target),
+		reports:    make(map[string]*Report),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Add 添加需要校验的仓库存储。remove 用于删除已隔离的构件，
+// hosted 仓库传入仓库的 Delete，以便同时清理校验和文件并更新 maven-metadata.xml
+func (s *Scrubber) Add(id string, repoStorage storage.Storage, remove func(path string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets[id] = target{storage: repoStorage, remove: remove}
+}
+
+// Start 按周期在后台执行校验，首次校验在一个周期后进行
+func (s *Scrubber) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.Run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台校验
+func (s *Scrubber) Stop() {
+	close(s.stopChan)
+}
+
+// Trigger 在后台立即执行一次校验，已有校验在执行时返回 false
+func (s *Scrubber) Trigger() bool {
+	if !s.begin() {
+		return false
+	}
+	go s.runAll()
+	return true
+}
+
+// Run 执行一次校验，已有校验在执行时直接返回
+func (s *Scrubber) Run() {
+	if !s.begin() {
+		return
+	}
+	s.runAll()
+}
+
+// Status 返回当前状态和各仓库最近一次的校验结果
+func (s *Scrubber) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports := make(map[string]*Report, len(s.reports))
+	for id, report := range s.reports {
+		reports[id] = report
+	}
+	return Status{
+		Running:  s.running,
+		Interval: s.interval.String(),
+		LastRun:  s.lastRun,
+		Reports:  reports,
+	}
+}
+
+// begin 标记校验开始，保证同一时间只有一次校验
+func (s *Scrubber) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return false
+	}
+	s.running = true
+	return true
+}
+
+func (s *Scrubber) runAll() {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.targets))
+	for id := range s.targets {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s.mu.Lock()
+		t := s.targets[id]
+		s.mu.Unlock()
+
+		report := s.scrub(id, t)
+		log.Infof("scrub %s finished: %d files, %d verified, %d corrupt, %d missing checksums, %d orphan checksums",
+			id, report.Files, report.Verified, len(report.Corrupt), len(report.MissingChecksums), len(report.OrphanChecksums))
+
+		s.mu.Lock()
+		s.reports[id] = report
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.running = false
+	s.lastRun = time.Now()
+	s.mu.Unlock()
+}
+
+// scrub 校验单个仓库
+func (s *Scrubber) scrub(id string, t target) *Report {
+	report := &Report{
+		Repository:       id,
+		StartTime:        time.Now(),
+		Corrupt:          []Issue{},
+		MissingChecksums: []string{},
+		OrphanChecksums:  []string{},
+	}
+
+	if err := s.scrubDir(id, t, "/", report); err != nil {
+		report.Error = err.Error()
+		log.Warnf("scrub %s failed: %v", id, err)
+	}
+	report.EndTime = time.Now()
+	return report
+}
+
+// scrubDir 校验目录，校验和文件与构件位于同一目录，因此按目录处理
+func (s *Scrubber) scrubDir(id string, t target, dir string, report *Report) error {
+	entries, err := t.storage.List(dir)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || !t.storage.Exists(dir) {
+			return nil
+		}
+		return err
+	}
+
+	files := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir {
+			files[entry.Name] = true
+		}
+	}
+
+	for _, entry := range entries {
+		p := path.Join(dir, entry.Name)
+		if entry.IsDir {
+			if err := s.scrubDir(id, t, p, report); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if algorithm := checksumType(entry.Name); algorithm != "" {
+			if !files[strings.TrimSuffix(entry.Name, "."+algorithm)] {
+				report.OrphanChecksums = append(report.OrphanChecksums, p)
+			}
+			continue
+		}
+
+		report.Files++
+		algorithms := []string{}
+		for _, algorithm := range checksumTypes {
+			if files[entry.Name+"."+algorithm] {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+		if len(algorithms) == 0 {
+			if util.NeedsHash(p) {
+				report.MissingChecksums = append(report.MissingChecksums, p)
+			}
+			continue
+		}
+
+		issue := s.verify(t.storage, p, algorithms)
+		if issue == nil {
+			report.Verified++
+			continue
+		}
+
+		log.Warnf("scrub %s: %s is corrupt: %s", id, p, describe(issue))
+		if s.quarantine != nil && issue.Error == "" {
+			if err := s.moveToQuarantine(id, t, p, algorithms); err != nil {
+				log.Warnf("quarantine %s/%s failed: %v", id, p, err)
+			} else {
+				issue.Quarantined = true
+			}
+		}
+		report.Corrupt = append(report.Corrupt, *issue)
+	}
+	return nil
+}
+
+// verify 重新计算摘要并与校验和文件比对，全部匹配时返回 nil
+func (s *Scrubber) verify(store storage.Storage, p string, algorithms []string) *Issue {
+	content, err := store.Read(p)
+	if err != nil {
+		return &Issue{Path: p, Error: err.Error()}
+	}
+	sums, err := util.ComputeHashes(content, algorithms...)
+	content.Close()
+	if err != nil {
+		return &Issue{Path: p, Error: err.Error()}
+	}
+
+	for _, algorithm := range algorithms {
+		expected, err := readChecksum(store, p+"."+algorithm)
+		if err != nil {
+			return &Issue{Path: p, Algorithm: algorithm, Error: err.Error()}
+		}
+		if !strings.EqualFold(expected, sums[algorithm]) {
+			return &Issue{Path: p, Algorithm: algorithm, Expected: expected, Actual: sums[algorithm]}
+		}
+	}
+	return nil
+}
+
+// moveToQuarantine 将构件和它的校验和文件移到隔离区
+func (s *Scrubber) moveToQuarantine(id string, t target, p string, algorithms []string) error {
+	paths := []string{p}
+	for _, algorithm := range algorithms {
+		paths = append(paths, p+"."+algorithm)
+	}
+
+	for _, file := range paths {
+		content, err := t.storage.Read(file)
+		if err != nil {
+			return err
+		}
+		err = s.quarantine.Write(path.Join("/", id, file), content, content.Size)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	remove := t.remove
+	if remove == nil {
+		remove = t.storage.Delete
+	}
+	if err := remove(p); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	for _, file := range paths[1:] {
+		if err := t.storage.Delete(file); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChecksum 读取校验和文件，兼容 "<摘要>  <文件名>" 格式
+func readChecksum(store storage.Storage, p string) (string, error) {
+	content, err := store.Read(p)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, maxChecksumSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxChecksumSize {
+		return "", fmt.Errorf("checksum file %s is too large", p)
+	}
+
+	fields := bytes.Fields(data)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", p)
+	}
+	return string(fields[0]), nil
+}
+
+// checksumType 返回校验和文件对应的算法，不是校验和文件时返回空字符串
+func checksumType(name string) string {
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	for _, algorithm := range checksumTypes {
+		if ext == algorithm {
+			return algorithm
+		}
+	}
+	return ""
+}
+
+func describe(issue *Issue) string {
+	if issue.Error != "" {
+		return issue.Error
+	}
+	return fmt.Sprintf("%s expected %s, actual %s", issue.Algorithm, issue.Expected, issue.Actual)
+}
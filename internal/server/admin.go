@@ -0,0 +1,299 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"maven-proxy/pkg/bundle"
+	"maven-proxy/pkg/index"
+	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleCacheStats 返回各仓库内存缓存的命中统计
+func (s *Server) handleCacheStats(c *gin.Context) {
+	stats := make(map[string]storage.MemoryCacheStats, len(s.memoryCaches))
+	for id, cache := range s.memoryCaches {
+		stats[id] = cache.Stats()
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleTierStats 返回各仓库分层存储的迁移统计
+func (s *Server) handleTierStats(c *gin.Context) {
+	stats := make(map[string]storage.TieredStats, len(s.tiers))
+	for id, tiered := range s.tiers {
+		stats[id] = tiered.Stats()
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleEvictionStats 返回各 proxy 仓库缓存淘汰的统计
+func (s *Server) handleEvictionStats(c *gin.Context) {
+	stats := make(map[string]storage.EvictionStats, len(s.evictions))
+	for id, evicting := range s.evictions {
+		stats[id] = evicting.Stats()
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleNotFoundStats 返回各 proxy 仓库 404 缓存的统计
+func (s *Server) handleNotFoundStats(c *gin.Context) {
+	stats := make(map[string]repository.NotFoundCacheStats, len(s.notFound))
+	for id, cache := range s.notFound {
+		stats[id] = cache.Stats()
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleNotFoundClear 清除 404 缓存，repo 参数指定仓库，prefix 参数指定路径前缀，都为空时清除所有记录
+func (s *Server) handleNotFoundClear(c *gin.Context) {
+	repoId, prefix := c.Query("repo"), c.Query("prefix")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if repoId != "" {
+		if _, ok := s.notFound[repoId]; !ok {
+			c.String(http.StatusNotFound, "repository %s has no not-found cache", repoId)
+			return
+		}
+	}
+
+	cleared := make(map[string]int)
+	for id, cache := range s.notFound {
+		if repoId == "" || id == repoId {
+			cleared[id] = cache.Clear(prefix)
+		}
+	}
+	c.JSON(http.StatusOK, cleared)
+}
+
+// handleScrubStatus 返回完整性校验的状态和各仓库最近一次的结果
+func (s *Server) handleScrubStatus(c *gin.Context) {
+	if s.scrubber == nil {
+		c.String(http.StatusNotFound, "scrub not enabled")
+		return
+	}
+	c.JSON(http.StatusOK, s.scrubber.Status())
+}
+
+// handleScrubRun 立即在后台执行一次完整性校验
+func (s *Server) handleScrubRun(c *gin.Context) {
+	if s.scrubber == nil {
+		c.String(http.StatusNotFound, "scrub not enabled")
+		return
+	}
+	if !s.scrubber.Trigger() {
+		c.String(http.StatusConflict, "scrub already running")
+		return
+	}
+	c.String(http.StatusAccepted, "scrub started")
+}
+
+// handleVolumeStats 返回各分片存储每个卷的使用情况
+func (s *Server) handleVolumeStats(c *gin.Context) {
+	if len(s.sharded) == 0 {
+		c.String(http.StatusNotFound, "sharded storage not enabled")
+		return
+	}
+	stats := make([]storage.ShardStats, 0, len(s.sharded))
+	for _, sharded := range s.sharded {
+		stats = append(stats, sharded.Stats())
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleRebalance 立即在后台重新平衡所有分片存储
+func (s *Server) handleRebalance(c *gin.Context) {
+	if len(s.sharded) == 0 {
+		c.String(http.StatusNotFound, "sharded storage not enabled")
+		return
+	}
+	started := 0
+	for _, sharded := range s.sharded {
+		if sharded.Trigger() {
+			started++
+		}
+	}
+	if started == 0 {
+		c.String(http.StatusConflict, "rebalance already running")
+		return
+	}
+	c.String(http.StatusAccepted, "rebalance started")
+}
+
+// handleIndexSearch 按坐标或路径搜索索引
+func (s *Server) handleIndexSearch(c *gin.Context) {
+	if s.index == nil {
+		c.String(http.StatusNotFound, "index not enabled")
+		return
+	}
+
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.String(http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := s.index.Search(index.Query{
+		Repository: c.Query("repo"),
+		GroupId:    c.Query("g"),
+		ArtifactId: c.Query("a"),
+		Version:    c.Query("v"),
+		Classifier: c.Query("c"),
+		Extension:  c.Query("e"),
+		Name:       c.Query("q"),
+		Limit:      limit,
+	})
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// handleIndexStats 返回各仓库的索引统计
+func (s *Server) handleIndexStats(c *gin.Context) {
+	if s.index == nil {
+		c.String(http.StatusNotFound, "index not enabled")
+		return
+	}
+
+	stats, err := s.index.Stats()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleIndexRebuild 在后台从存储重建索引，可通过 repo 参数指定仓库
+func (s *Server) handleIndexRebuild(c *gin.Context) {
+	if s.index == nil {
+		c.String(http.StatusNotFound, "index not enabled")
+		return
+	}
+
+	ids := []string{}
+	if repo := c.Query("repo"); repo != "" {
+		if _, ok := s.indexed[repo]; !ok {
+			c.String(http.StatusNotFound, "repository not found")
+			return
+		}
+		ids = append(ids, repo)
+	} else {
+		for id := range s.indexed {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+
+	if !s.rebuilding.TryLock() {
+		c.String(http.StatusConflict, "index rebuild already running")
+		return
+	}
+	go func() {
+		defer s.rebuilding.Unlock()
+		for _, id := range ids {
+			count, err := s.indexed[id].Rebuild()
+			if err != nil {
+				log.Printf("rebuild index of %s failed: %v", id, err)
+				continue
+			}
+			log.Printf("rebuilt index of %s: %d files", id, count)
+		}
+	}()
+	c.String(http.StatusAccepted, "index rebuild started")
+}
+
+// handleExport 将仓库或 group 参数指定的 groupId 导出为归档，format 为 tar.gz 或 zip
+func (s *Server) handleExport(c *gin.Context) {
+	repo, exists := s.repositories[c.Param("repoId")]
+	if !exists {
+		c.String(http.StatusNotFound, "repository not found")
+		return
+	}
+	if repo.Type() == "group" {
+		c.String(http.StatusBadRequest, "group repository can not be exported")
+		return
+	}
+
+	format := c.DefaultQuery("format", bundle.FormatTarGz)
+	if format != bundle.FormatTarGz && format != bundle.FormatZip {
+		c.String(http.StatusBadRequest, "unsupported bundle format")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", repo.ID()+"."+format))
+	c.Header("Content-Type", "application/octet-stream")
+	if _, err := bundle.Export(repo, bundle.GroupRoot(c.Query("group")), format, c.Writer); err != nil {
+		// 已经开始输出时无法再修改状态码，客户端会收到不完整的归档
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.String(http.StatusInternalServerError, err.Error())
+		}
+		log.Printf("export %s failed: %v", repo.ID(), err)
+	}
+}
+
+// handleImport 将请求体中的归档导入 hosted 仓库，conflict 参数指定冲突处理方式
+func (s *Server) handleImport(c *gin.Context) {
+	repo, exists := s.repositories[c.Param("repoId")]
+	if !exists {
+		c.String(http.StatusNotFound, "repository not found")
+		return
+	}
+	if repo.Type() != "hosted" || !repo.CanWrite() {
+		c.String(http.StatusForbidden, "repository not support write")
+		return
+	}
+
+	policy, err := bundle.ParsePolicy(c.Query("conflict"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// zip 需要随机读取，先保存到临时文件
+	tmp, err := os.CreateTemp("", "maven-proxy-bundle-*")
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, c.Request.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "data read failed")
+		return
+	}
+
+	result, err := bundle.Import(repo, tmp.Name(), policy)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, bundle.ErrConflict):
+			status = http.StatusConflict
+		case errors.Is(err, storage.ErrQuotaExceeded):
+			status = http.StatusInsufficientStorage
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -2,8 +2,8 @@
 package server
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"path"
 	"path/filepath"
@@ -11,14 +11,19 @@ import (
 	"strings"
 
 	"maven-proxy/internal/util"
+	"maven-proxy/pkg/event"
 	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) handleGet(c *gin.Context) {
 	repoId := c.Param("repoId")
-	filePath := c.Param("path")
+	filePath, ok := requestPath(c)
+	if !ok {
+		return
+	}
 
 	repo, exists := s.repositories[repoId]
 	if !exists {
@@ -46,33 +51,108 @@ func (s *Server) handleGet(c *gin.Context) {
 		}
 	}
 
+	generate := strings.EqualFold(c.Query("generate_md5_sha1"), "true")
+
+	// 已存储的文件通过 Stat 响应 HEAD 和条件请求，不需要读取内容
+	info, err := repo.Stat(filePath)
+	if err == nil && !info.IsDir {
+		etag := fileETag(info)
+		c.Header("ETag", etag)
+		c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			repo.Touch(filePath)
+			return
+		}
+		if c.Request.Method == http.MethodHead && !generate {
+			c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+			c.Header("Content-Type", info.ContentType)
+			c.Status(http.StatusOK)
+			repo.Touch(filePath)
+			return
+		}
+	} else if s.serveRecordedChecksum(c, repo, filePath) {
+		// 校验和文件不存在时使用原文件写入时记录的摘要
+		return
+	}
+
 	// 获取文件内容
-	data, status, headers, err := repo.Get(filePath)
+	content, status, err := repo.Get(filePath)
 	if err != nil {
 		c.String(status, err.Error())
 		return
 	}
+	defer content.Close()
 
 	// 处理哈希生成
-	if generate := c.Query("generate_md5_sha1"); strings.EqualFold(generate, "true") {
+	if generate {
 		// 对于 hosted 仓库，生成哈希文件
 		if repo.Type() == "hosted" {
-			localPath := path.Join(s.config.LocalRepository, repo.ID(), filePath)
-			if err := util.GenerateHash(localPath); err != nil {
+			if err := s.generateHash(repo, filePath); err != nil {
 				c.String(http.StatusInternalServerError, "generate hash failed")
 				return
 			}
 		}
 	}
 
-	c.Data(status, headers.Get("Content-Type"), data)
+	c.DataFromReader(status, content.Size, content.ContentType, content, nil)
+	// 只有客户端请求记录访问，后台任务的读取不影响冷热分层和缓存淘汰
+	repo.Touch(filePath)
+}
+
+// serveRecordedChecksum 使用 Stat 返回的摘要响应 .sha1 和 .sha256 请求
+func (s *Server) serveRecordedChecksum(c *gin.Context, repo repository.Repository, filePath string) bool {
+	var digest func(*storage.FileInfo) string
+	switch path.Ext(filePath) {
+	case ".sha1":
+		digest = func(info *storage.FileInfo) string { return info.SHA1 }
+	case ".sha256":
+		digest = func(info *storage.FileInfo) string { return info.SHA256 }
+	default:
+		return false
+	}
+
+	info, err := repo.Stat(strings.TrimSuffix(filePath, path.Ext(filePath)))
+	if err != nil || info.IsDir || digest(info) == "" {
+		return false
+	}
+
+	c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "text/plain", []byte(digest(info)))
+	return true
+}
+
+// fileETag 优先使用 SHA-1 作为强 ETag，没有摘要时使用长度和修改时间生成弱 ETag
+func fileETag(info *storage.FileInfo) string {
+	if info.SHA1 != "" {
+		return `"` + info.SHA1 + `"`
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size, info.ModTime.UnixNano())
+}
+
+// etagMatches 检查 If-None-Match 是否包含指定 ETag
+func etagMatches(header string, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Server) handlePut(c *gin.Context) {
 	// 认证已经在中间件中完成
 
 	repoId := c.Param("repoId")
-	filePath := c.Param("path")
+	filePath, ok := requestPath(c)
+	if !ok {
+		return
+	}
 
 	repo, exists := s.repositories[repoId]
 	if !exists {
@@ -85,16 +165,23 @@ func (s *Server) handlePut(c *gin.Context) {
 		return
 	}
 
-	// 读取请求体
-	length, err1 := strconv.Atoi(c.GetHeader("Content-Length"))
-	data, err2 := ioutil.ReadAll(c.Request.Body)
-	if err1 != nil || err2 != nil || length <= 0 || length != len(data) {
+	// 请求体以流的方式写入存储，长度不符时由存储层拒绝
+	length := c.Request.ContentLength
+	if length <= 0 {
 		c.String(http.StatusInternalServerError, "data read failed")
 		return
 	}
 
 	// 上传文件
-	if err := repo.Put(filePath, data); err != nil {
+	if err := repo.Put(filePath, c.Request.Body, length); err != nil {
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			c.String(http.StatusInsufficientStorage, err.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidPath) {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
 		c.String(http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -102,17 +189,114 @@ func (s *Server) handlePut(c *gin.Context) {
 	// 处理哈希生成
 	if generate := c.Query("generate_md5_sha1"); strings.EqualFold(generate, "true") {
 		if repo.Type() == "hosted" {
-			localPath := path.Join(s.config.LocalRepository, repo.ID(), filePath)
-			if err := util.GenerateHash(localPath); err != nil {
+			if err := s.generateHash(repo, filePath); err != nil {
 				c.String(http.StatusInternalServerError, "generate hash failed")
 				return
 			}
 		}
 	}
 
+	s.events.Publish(event.Event{
+		Type:       event.Deployed,
+		Repository: repoId,
+		Path:       filePath,
+		Source:     event.SourceHTTP,
+	})
 	c.String(http.StatusOK, "OK")
 }
 
+func (s *Server) handleDelete(c *gin.Context) {
+	// 认证已经在中间件中完成
+
+	repoId := c.Param("repoId")
+	filePath, ok := requestPath(c)
+	if !ok {
+		return
+	}
+
+	repo, exists := s.repositories[repoId]
+	if !exists {
+		c.String(http.StatusNotFound, "repository not found")
+		return
+	}
+
+	// proxy 和 group 仓库没有可删除的自有内容
+	if repo.Type() != "hosted" {
+		c.String(http.StatusMethodNotAllowed, fmt.Sprintf("%s repository not support delete", repo.Type()))
+		return
+	}
+
+	if !repo.CanDelete() {
+		c.String(http.StatusForbidden, "repository not support delete")
+		return
+	}
+
+	if strings.Trim(filePath, "/") == "" {
+		c.String(http.StatusBadRequest, "refuse to delete repository root")
+		return
+	}
+
+	if err := repo.Delete(filePath); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			c.String(http.StatusNotFound, "file not found")
+		case errors.Is(err, repository.ErrNotSupported):
+			c.String(http.StatusMethodNotAllowed, err.Error())
+		default:
+			c.String(http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.events.Publish(event.Event{
+		Type:       event.Deleted,
+		Repository: repoId,
+		Path:       filePath,
+		Source:     event.SourceHTTP,
+	})
+	c.String(http.StatusOK, "OK")
+}
+
+// requestPath 返回规范化后的请求路径，路径不合法时响应 400
+func requestPath(c *gin.Context) (string, bool) {
+	filePath, err := storage.NormalizeRequestPath(c.Param("path"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return filePath, true
+}
+
+// generateHash 通过仓库读取文件并生成缺失的 md5 和 sha1 校验和文件
+func (s *Server) generateHash(repo repository.Repository, filePath string) error {
+	if !util.NeedsHash(filePath) {
+		return nil
+	}
+
+	content, _, err := repo.Get(filePath)
+	if err != nil {
+		return err
+	}
+	sums, err := util.ComputeHashes(content, "md5", "sha1")
+	content.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, hashType := range []string{"md5", "sha1"} {
+		hashPath := filePath + "." + hashType
+		if existing, _, err := repo.Get(hashPath); err == nil {
+			existing.Close()
+			continue
+		}
+		sum := sums[hashType]
+		if err := repo.Put(hashPath, strings.NewReader(sum), int64(len(sum))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Server) renderDirectoryListing(repo repository.Repository, filePath string) (string, error) {
 	entries, err := repo.List(filePath)
 	if err != nil {
@@ -162,7 +346,10 @@ func (s *Server) renderDirectoryListing(repo repository.Repository, filePath str
 			name += "/"
 		}
 
-		modTime := entry.ModTime.Format("2006-01-02 15:04:05")
+		modTime := "-"
+		if !entry.ModTime.IsZero() {
+			modTime = entry.ModTime.Format("2006-01-02 15:04:05")
+		}
 		linkPath := path.Join(filePath, entry.Name)
 		if entry.IsDir {
 			linkPath += "/"
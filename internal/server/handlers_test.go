@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"maven-proxy/pkg/auth"
+	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestPathRejectsHostilePaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "releases")
+	if err := os.MkdirAll(filepath.Join(repoDir, "com"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// 仓库目录之外的文件，不能通过任何请求读到
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	users := []*config.User{{Name: "u", Password: "p"}}
+	srv := NewServer(&config.Config{Context: "maven"}, auth.NewBasicAuthenticator(users))
+	srv.RegisterRepository("releases", repository.NewHostedRepository("releases", 7, storage.NewFileSystemStorage(repoDir)))
+
+	tests := []struct {
+		method string
+		target string
+	}{
+		{http.MethodGet, "/maven/releases/com/%2e%2e/%2e%2e/secret.txt"},
+		{http.MethodGet, "/maven/releases/com/%2E%2E/%2E%2E/secret.txt"},
+		{http.MethodGet, "/maven/releases/..%2f..%2fsecret.txt"},
+		{http.MethodGet, "/maven/releases/com%5c..%5c..%5csecret.txt"},
+		{http.MethodGet, "/maven/releases/com/%252e%252e/secret.txt"},
+		{http.MethodGet, "/maven/releases/com/lib%00.jar"},
+		{http.MethodGet, "/maven/releases/com/.lib.jar.attributes"},
+		{http.MethodGet, "/maven/releases/C:/secret.txt"},
+		{http.MethodGet, "/maven/releases/com/NUL.jar"},
+		{http.MethodGet, "/maven/releases/com/" + strings.Repeat("a", 256) + ".jar"},
+		{http.MethodHead, "/maven/releases/com/%2e%2e/%2e%2e/secret.txt"},
+		{http.MethodPut, "/maven/releases/com/%2e%2e/%2e%2e/evil.jar"},
+		{http.MethodDelete, "/maven/releases/com/%2e%2e/%2e%2e/secret.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader("data"))
+			req.SetBasicAuth("u", "p")
+			w := httptest.NewRecorder()
+			srv.engine.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d (body %q)", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+			if w.Body.String() == "secret" {
+				t.Error("response leaks file outside repository")
+			}
+		})
+	}
+
+	// 合法路径不受影响
+	req := httptest.NewRequest(http.MethodGet, "/maven/releases/com/lib-1.0.jar", nil)
+	w := httptest.NewRecorder()
+	srv.engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("valid path status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "evil.jar")); err == nil {
+		t.Error("PUT wrote a file outside the repository")
+	}
+	if _, err := os.Stat(filepath.Join(root, "secret.txt")); err != nil {
+		t.Errorf("DELETE removed a file outside the repository: %v", err)
+	}
+}
@@ -2,9 +2,15 @@
 package server
 
 import (
+	"sync"
+
+	"maven-proxy/internal/scrub"
 	"maven-proxy/pkg/auth"
 	"maven-proxy/pkg/config"
+	"maven-proxy/pkg/event"
+	"maven-proxy/pkg/index"
 	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +20,16 @@ type Server struct {
 	engine        *gin.Engine
 	repositories  map[string]repository.Repository
 	authenticator auth.Authenticator
+	memoryCaches  map[string]*storage.MemoryCacheStorage
+	tiers         map[string]*storage.TieredStorage
+	evictions     map[string]*storage.EvictingStorage
+	notFound      map[string]*repository.NotFoundCache
+	scrubber      *scrub.Scrubber
+	index         *index.Index
+	indexed       map[string]*index.IndexedStorage
+	rebuilding    sync.Mutex
+	events        *event.Bus
+	sharded       []*storage.ShardedStorage
 }
 
 func NewServer(cfg *config.Config, authenticator auth.Authenticator) *Server {
@@ -22,6 +38,12 @@ func NewServer(cfg *config.Config, authenticator auth.Authenticator) *Server {
 		engine:        gin.Default(),
 		repositories:  make(map[string]repository.Repository),
 		authenticator: authenticator,
+		memoryCaches:  make(map[string]*storage.MemoryCacheStorage),
+		tiers:         make(map[string]*storage.TieredStorage),
+		evictions:     make(map[string]*storage.EvictingStorage),
+		notFound:      make(map[string]*repository.NotFoundCache),
+		indexed:       make(map[string]*index.IndexedStorage),
+		events:        event.NewBus(),
 	}
 
 	s.setupRoutes()
@@ -29,20 +51,85 @@ func NewServer(cfg *config.Config, authenticator auth.Authenticator) *Server {
 }
 
 func (s *Server) setupRoutes() {
+	// 管理接口需要认证
+	admin := s.engine.Group("/_admin", auth.Middleware(s.authenticator))
+	admin.GET("/cache", s.handleCacheStats)
+	admin.GET("/tiers", s.handleTierStats)
+	admin.GET("/eviction", s.handleEvictionStats)
+	admin.GET("/notfound", s.handleNotFoundStats)
+	admin.POST("/notfound/clear", s.handleNotFoundClear)
+	admin.GET("/scrub", s.handleScrubStatus)
+	admin.POST("/scrub", s.handleScrubRun)
+	admin.GET("/index/search", s.handleIndexSearch)
+	admin.GET("/index/stats", s.handleIndexStats)
+	admin.POST("/index/rebuild", s.handleIndexRebuild)
+	admin.GET("/volumes", s.handleVolumeStats)
+	admin.POST("/volumes/rebalance", s.handleRebalance)
+	admin.GET("/export/:repoId", s.handleExport)
+	admin.POST("/import/:repoId", s.handleImport)
+
 	// GET 和 HEAD 不需要认证
 	s.engine.GET("/:context/:repoId/*path", s.handleGet)
 	s.engine.HEAD("/:context/:repoId/*path", s.handleGet)
 
-	// PUT 需要认证
+	// PUT 和 DELETE 需要认证
 	s.engine.PUT("/:context/:repoId/*path",
 		auth.Middleware(s.authenticator),
 		s.handlePut)
+	s.engine.DELETE("/:context/:repoId/*path",
+		auth.Middleware(s.authenticator),
+		s.handleDelete)
 }
 
 func (s *Server) RegisterRepository(id string, repo repository.Repository) {
 	s.repositories[id] = repo
 }
 
+// RegisterMemoryCache 注册仓库的内存缓存，用于统计信息查询
+func (s *Server) RegisterMemoryCache(id string, cache *storage.MemoryCacheStorage) {
+	s.memoryCaches[id] = cache
+}
+
+// RegisterTieredStorage 注册仓库的分层存储，用于统计信息查询
+func (s *Server) RegisterTieredStorage(id string, tiered *storage.TieredStorage) {
+	s.tiers[id] = tiered
+}
+
+// RegisterEvictingStorage 注册 proxy 仓库的缓存淘汰包装器，用于统计信息查询
+func (s *Server) RegisterEvictingStorage(id string, evicting *storage.EvictingStorage) {
+	s.evictions[id] = evicting
+}
+
+// RegisterNotFoundCache 注册 proxy 仓库的 404 缓存，用于统计信息查询和清除
+func (s *Server) RegisterNotFoundCache(id string, cache *repository.NotFoundCache) {
+	s.notFound[id] = cache
+}
+
+// SetScrubber 设置完整性校验器，用于查询结果和手动触发
+func (s *Server) SetScrubber(scrubber *scrub.Scrubber) {
+	s.scrubber = scrubber
+}
+
+// SetIndex 设置文件索引，用于搜索和统计
+func (s *Server) SetIndex(idx *index.Index) {
+	s.index = idx
+}
+
+// RegisterShardedStorage 注册分片存储后端，用于查询各卷使用情况和手动重新平衡
+func (s *Server) RegisterShardedStorage(sharded *storage.ShardedStorage) {
+	s.sharded = append(s.sharded, sharded)
+}
+
+// RegisterIndexedStorage 注册仓库的索引包装器，用于重建索引
+func (s *Server) RegisterIndexedStorage(id string, indexed *index.IndexedStorage) {
+	s.indexed[id] = indexed
+}
+
+// Events 返回仓库内容变更的事件总线
+func (s *Server) Events() *event.Bus {
+	return s.events
+}
+
 func (s *Server) Run() error {
 	addr := s.config.Listen + ":" + s.config.Port
 	return s.engine.Run(addr)
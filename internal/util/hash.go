@@ -7,6 +7,8 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -31,8 +33,7 @@ func GenerateHash(file string) error {
 		return nil
 	}
 
-	ext := path.Ext(file)
-	if ext != ".xml" && ext != ".jar" && ext != ".pom" {
+	if !NeedsHash(file) {
 		return nil
 	}
 
@@ -79,6 +80,51 @@ func GetHash(file []byte, hashType string) []byte {
 	}
 }
 
+// NeedsHash 判断文件是否需要生成校验和文件
+func NeedsHash(file string) bool {
+	ext := path.Ext(file)
+	return ext == ".xml" || ext == ".jar" || ext == ".pom"
+}
+
+// ComputeHashes 流式计算多种校验和，返回类型到十六进制字符串的映射
+func ComputeHashes(r io.Reader, hashTypes ...string) (map[string]string, error) {
+	hashers := make(map[string]hash.Hash, len(hashTypes))
+	writers := make([]io.Writer, 0, len(hashTypes))
+	for _, hashType := range hashTypes {
+		h := newHash(hashType)
+		if h == nil {
+			return nil, fmt.Errorf("unsupported hash type: %s", hashType)
+		}
+		hashers[hashType] = h
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]string, len(hashers))
+	for hashType, h := range hashers {
+		sums[hashType] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return sums, nil
+}
+
+func newHash(hashType string) hash.Hash {
+	switch hashType {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	default:
+		return nil
+	}
+}
+
 // FileExists 检查文件是否存在
 func FileExists(file string) (bool, error) {
 	if _, err := os.Stat(file); err != nil && os.IsNotExist(err) {
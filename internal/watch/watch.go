@@ -0,0 +1,307 @@
+// internal/watch/watch.go
+package watch
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"maven-proxy/internal/util"
+	"maven-proxy/pkg/event"
+	"maven-proxy/pkg/storage"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetLevel(logrus.InfoLevel)
+	log.SetFormatter(&logrus.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+	})
+}
+
+// hashTypes 为直接放入的文件补充的校验和类型
+var hashTypes = []string{"md5", "sha1"}
+
+// fileState 文件的长度和修改时间，用于判断文件是否变化
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// target 监视的仓库目录
+type target struct {
+	id      string
+	dir     string
+	storage storage.Storage // 仓库使用的存储，包含配额、内存缓存和索引等包装器
+
+	scanned bool                 // 是否已完成首次扫描，首次扫描只记录基线
+	known   map[string]fileState // 基线中的文件和已处理的文件，未变化时不再检查
+	pending map[string]fileState // 上次扫描发现的新文件，两次扫描之间没有变化才处理
+}
+
+// Watcher 定期扫描 hosted 仓库目录，发现绕过 HTTP 直接放入的文件后通过仓库存储重新写入，
+// 生成缺失的校验和，并发布与 HTTP 上传相同的事件
+//
+// 首次扫描时目录中已有的文件作为基线，不做处理。之后出现或发生变化的文件，如果没有与之一致的
+// 属性文件（即不是通过存储写入的），就是直接放入的文件。服务停止期间放入的文件计入基线，
+// 可以 touch 后由下次扫描处理
+//
+// 处理时以原文件的内容通过仓库存储重新写入，存储先写临时文件再重命名，原路径始终可以访问。
+// 与 HTTP 上传使用同一个存储，同样加锁并更新内存缓存和索引；对配额而言是替换已有文件，
+// 直接放入的文件在重启重新统计使用量后才计入
+type Watcher struct {
+	interval time.Duration
+	bus      *event.Bus
+
+	mu       sync.Mutex
+	targets  []*target
+	stopChan chan struct{}
+}
+
+// NewWatcher 创建目录监视器
+func NewWatcher(interval time.Duration, bus *event.Bus) *Watcher {
+	return &Watcher{
+		interval: interval,
+		bus:      bus,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Add 添加监视的仓库目录，repoStorage 为仓库使用的存储，其中的路径与目录下的相对路径一致
+func (w *Watcher) Add(id string, dir string, repoStorage storage.Storage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.targets = append(w.targets, &target{
+		id:      id,
+		dir:     dir,
+		storage: repoStorage,
+		known:   make(map[string]fileState),
+		pending: make(map[string]fileState),
+	})
+}
+
+// Start 按周期在后台扫描
+func (w *Watcher) Start() {
+	if w.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.Scan()
+			select {
+			case <-ticker.C:
+			case <-w.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止扫描
+func (w *Watcher) Stop() {
+	close(w.stopChan)
+}
+
+// Scan 扫描一次所有仓库目录，返回处理的文件数
+func (w *Watcher) Scan() int {
+	w.mu.Lock()
+	targets := append([]*target(nil), w.targets...)
+	w.mu.Unlock()
+
+	total := 0
+	for _, t := range targets {
+		count, err := w.scan(t)
+		if err != nil {
+			log.Warnf("watch %s failed: %v", t.id, err)
+		}
+		if count > 0 {
+			log.Infof("picked up %d files dropped into %s", count, t.id)
+		}
+		total += count
+	}
+	return total
+}
+
+// scan 扫描单个仓库目录
+func (w *Watcher) scan(t *target) (int, error) {
+	known := make(map[string]fileState, len(t.known))
+	pending := make(map[string]fileState)
+	ready := []trackedFile{}
+
+	err := filepath.WalkDir(t.dir, func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		// 跳过临时文件、属性文件等隐藏文件以及 rsync 等工具写入中的临时文件
+		if fullPath != t.dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(t.dir, fullPath)
+		if err != nil {
+			return err
+		}
+		p := "/" + filepath.ToSlash(rel)
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+
+		if !t.scanned {
+			known[p] = state
+			return nil
+		}
+		if prev, ok := t.known[p]; ok && prev == state {
+			known[p] = state
+			return nil
+		}
+		// 基线之后通过存储写入的文件，例如 HTTP 上传和生成的元数据
+		if stat, err := t.storage.Stat(p); err == nil && stat.SHA1 != "" {
+			known[p] = state
+			return nil
+		}
+
+		if prev, ok := t.pending[p]; ok && prev == state {
+			ready = append(ready, trackedFile{path: p, state: state})
+		} else {
+			pending[p] = state
+		}
+		return nil
+	})
+
+	if !t.scanned {
+		// 遍历出错时基线不完整，下次扫描重新记录，避免把遗漏的已有文件当作新文件
+		if err == nil {
+			t.scanned = true
+			log.Infof("watching %s: %d existing files", t.id, len(known))
+		}
+		t.known = known
+		return 0, err
+	}
+
+	count := 0
+	for _, file := range ready {
+		adopted, adoptErr := w.adopt(t, file)
+		for _, done := range adopted {
+			known[done.path] = done.state
+			delete(pending, done.path)
+		}
+		if adoptErr != nil {
+			log.Warnf("pick up %s%s failed: %v", t.id, file.path, adoptErr)
+			continue
+		}
+		count++
+	}
+
+	t.known = known
+	t.pending = pending
+	return count, err
+}
+
+// trackedFile 扫描到的文件及其状态
+type trackedFile struct {
+	path  string
+	state fileState
+}
+
+// adopt 以文件原有的内容通过仓库存储重新写入，补充缺失的校验和文件并发布事件，返回处理过的文件
+func (w *Watcher) adopt(t *target, file trackedFile) ([]trackedFile, error) {
+	p := file.path
+	fullPath := filepath.Join(t.dir, filepath.FromSlash(p))
+	sums, err := rewrite(t.storage, p, fullPath, file.state, util.NeedsHash(fullPath))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{p}
+	for _, hashType := range hashTypes {
+		sumPath := p + "." + hashType
+		if sums == nil || t.storage.Exists(sumPath) {
+			continue
+		}
+		if err := storage.WriteBytes(t.storage, sumPath, []byte(sums[hashType])); err != nil {
+			return nil, err
+		}
+		paths = append(paths, sumPath)
+	}
+
+	adopted := []trackedFile{}
+	for _, file := range paths {
+		info, err := os.Stat(filepath.Join(t.dir, filepath.FromSlash(file)))
+		if err != nil {
+			return adopted, err
+		}
+		adopted = append(adopted, trackedFile{
+			path:  file,
+			state: fileState{size: info.Size(), modTime: info.ModTime()},
+		})
+
+		w.bus.Publish(event.Event{
+			Type:       event.Deployed,
+			Repository: t.id,
+			Path:       path.Clean(file),
+			Source:     event.SourceWatcher,
+		})
+	}
+	return adopted, nil
+}
+
+// rewrite 以原文件的内容通过存储重新写入，needsHash 时返回文件的校验和。
+// 打开的文件与扫描时的状态不一致时放弃，等待下次扫描
+// 校验和流式计算后由调用方通过存储写入，不使用 util.GenerateHash 直接写入目录
+func rewrite(s storage.Storage, p, fullPath string, expected fileState, needsHash bool) (map[string]string, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if (fileState{size: stat.Size(), modTime: stat.ModTime()}) != expected {
+		return nil, fmt.Errorf("%s changed since last scan", p)
+	}
+
+	var sums map[string]string
+	if needsHash {
+		if sums, err = util.ComputeHashes(file, hashTypes...); err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Write(p, file, stat.Size()); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
@@ -0,0 +1,231 @@
+// pkg/bundle/bundle.go
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
+)
+
+// 归档格式
+const (
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+// 归档布局：清单位于第一个条目，文件位于 files/ 目录下，导入时可以先检查清单再写入
+const (
+	manifestName    = "manifest.json"
+	filesDir        = "files/"
+	manifestVersion = 1
+)
+
+// Manifest 归档清单，记录每个文件的长度和摘要
+type Manifest struct {
+	Version    int            `json:"version"`
+	Repository string         `json:"repository"`
+	Root       string         `json:"root"`
+	Created    time.Time      `json:"created"`
+	Files      []ManifestFile `json:"files"`
+}
+
+// ManifestFile 清单中的文件
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA1   string `json:"sha1"`
+	SHA256 string `json:"sha256"`
+}
+
+// GroupRoot 将 groupId 转换为仓库中的目录，如 org.example 对应 /org/example
+func GroupRoot(groupId string) string {
+	if groupId == "" {
+		return "/"
+	}
+	return "/" + strings.ReplaceAll(groupId, ".", "/")
+}
+
+// Export 将仓库中 root 目录下的文件导出为归档写入 w
+//
+// 先遍历一次生成清单，再按清单写入文件内容，写入时重新计算摘要，
+// 导出期间文件被修改时返回错误
+func Export(repo repository.Repository, root string, format string, w io.Writer) (*Manifest, error) {
+	if format != FormatTarGz && format != FormatZip {
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+	root, err := storage.NormalizeRequestPath(root)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Version:    manifestVersion,
+		Repository: repo.ID(),
+		Root:       strings.TrimSuffix(root, "/"),
+		Created:    time.Now().UTC(),
+		Files:      []ManifestFile{},
+	}
+	if manifest.Root == "" {
+		manifest.Root = "/"
+	}
+
+	err = walk(repo, root, func(p string) error {
+		file, err := describe(repo, p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	archive := newArchiveWriter(format, w)
+	if err := archive.add(manifestName, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		if err := exportFile(repo, archive, file); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportFile 写入单个文件并校验与清单一致
+func exportFile(repo repository.Repository, archive *archiveWriter, file ManifestFile) error {
+	content, _, err := repo.Get(file.Path)
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", file.Path, err)
+	}
+	defer content.Close()
+
+	hasher := sha256.New()
+	if err := archive.add(filesDir+strings.TrimPrefix(file.Path, "/"), file.Size, io.TeeReader(content, hasher)); err != nil {
+		return fmt.Errorf("write %s failed: %w", file.Path, err)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%s changed during export", file.Path)
+	}
+	return nil
+}
+
+// describe 生成文件的清单条目，存储没有记录摘要时读取文件计算
+func describe(repo repository.Repository, p string) (*ManifestFile, error) {
+	info, err := repo.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	file := &ManifestFile{
+		Path:   p,
+		Size:   info.Size,
+		SHA1:   info.SHA1,
+		SHA256: info.SHA256,
+	}
+	if file.SHA1 != "" && file.SHA256 != "" {
+		return file, nil
+	}
+
+	content, _, err := repo.Get(p)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	sha1Hasher, sha256Hasher := sha1.New(), sha256.New()
+	n, err := io.Copy(io.MultiWriter(sha1Hasher, sha256Hasher), content)
+	if err != nil {
+		return nil, err
+	}
+	file.Size = n
+	file.SHA1 = hex.EncodeToString(sha1Hasher.Sum(nil))
+	file.SHA256 = hex.EncodeToString(sha256Hasher.Sum(nil))
+	return file, nil
+}
+
+// walk 递归遍历仓库目录下的所有文件
+func walk(repo repository.Repository, dir string, fn func(p string) error) error {
+	entries, err := repo.List(dir)
+	if err != nil {
+		return fmt.Errorf("list %s failed: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		p := path.Join(dir, entry.Name)
+		if entry.IsDir {
+			if err := walk(repo, p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveWriter 统一 tar.gz 和 zip 的写入
+type archiveWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+	zip *zip.Writer
+}
+
+func newArchiveWriter(format string, w io.Writer) *archiveWriter {
+	if format == FormatZip {
+		return &archiveWriter{zip: zip.NewWriter(w)}
+	}
+	gz := gzip.NewWriter(w)
+	return &archiveWriter{gz: gz, tar: tar.NewWriter(gz)}
+}
+
+func (a *archiveWriter) add(name string, size int64, r io.Reader) error {
+	now := time.Now()
+	if a.zip != nil {
+		fw, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, r)
+		return err
+	}
+
+	header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: now, Typeflag: tar.TypeReg}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tar, r)
+	return err
+}
+
+func (a *archiveWriter) Close() error {
+	if a.zip != nil {
+		return a.zip.Close()
+	}
+	if err := a.tar.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
@@ -0,0 +1,336 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"maven-proxy/pkg/repository"
+	"maven-proxy/pkg/storage"
+)
+
+// Policy 导入时目标仓库已存在同名文件的处理方式
+type Policy string
+
+const (
+	PolicySkip      Policy = "skip"      // 保留已有文件
+	PolicyOverwrite Policy = "overwrite" // 覆盖已有文件
+	PolicyFail      Policy = "fail"      // 存在冲突时不导入任何文件
+)
+
+// ErrConflict 归档中的文件与仓库中已有文件冲突
+var ErrConflict = errors.New("bundle conflicts with existing files")
+
+// maxManifestSize 清单的最大长度
+const maxManifestSize = 256 * 1024 * 1024
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Imported    int      `json:"imported"`
+	Skipped     int      `json:"skipped"`
+	Overwritten int      `json:"overwritten"`
+	Conflicts   []string `json:"conflicts"`
+}
+
+// ParsePolicy 解析冲突处理方式，为空时为 fail
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case "":
+		return PolicyFail, nil
+	case PolicySkip, PolicyOverwrite, PolicyFail:
+		return Policy(s), nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy: %s, expect skip, overwrite or fail", s)
+	}
+}
+
+// Import 将归档导入仓库，格式根据文件内容自动识别
+//
+// 写入前先按清单检查冲突，再将所有文件暂存到临时目录并校验长度和 SHA-256，
+// 全部校验通过后才写入仓库。写入仓库失败时删除本次新导入的文件，被覆盖的文件无法恢复
+func Import(repo repository.Repository, archivePath string, policy Policy) (*ImportResult, error) {
+	archive, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	manifest, err := readManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]ManifestFile, len(manifest.Files))
+	paths := make([]string, 0, len(manifest.Files))
+	result := &ImportResult{Conflicts: []string{}}
+	conflicts := make(map[string]bool)
+	for _, file := range manifest.Files {
+		p, err := storage.NormalizeRequestPath(file.Path)
+		if err != nil || strings.HasSuffix(p, "/") || p == "/" {
+			return nil, fmt.Errorf("invalid path in manifest: %q", file.Path)
+		}
+		if _, ok := files[p]; ok {
+			return nil, fmt.Errorf("duplicate path in manifest: %q", file.Path)
+		}
+		file.Path = p
+		files[p] = file
+		paths = append(paths, p)
+
+		if info, err := repo.Stat(p); err == nil && !info.IsDir {
+			conflicts[p] = true
+			result.Conflicts = append(result.Conflicts, p)
+		}
+	}
+	if policy == PolicyFail && len(conflicts) > 0 {
+		return result, fmt.Errorf("%w: %d files already exist", ErrConflict, len(conflicts))
+	}
+
+	stageDir, err := os.MkdirTemp("", "maven-proxy-import-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	staged, err := stageFiles(archive, files, stageDir)
+	if err != nil {
+		return result, err
+	}
+
+	imported := []string{}
+	for _, p := range paths {
+		if conflicts[p] && policy == PolicySkip {
+			result.Skipped++
+			continue
+		}
+		if err := putFile(repo, files[p], staged[p]); err != nil {
+			result.Imported = 0
+			return result, errors.Join(err, rollback(repo, imported))
+		}
+		if conflicts[p] {
+			result.Overwritten++
+		} else {
+			result.Imported++
+			imported = append(imported, p)
+		}
+	}
+	return result, nil
+}
+
+// stageFiles 将归档中的文件写入临时目录并按清单校验，返回路径到临时文件的映射
+func stageFiles(archive *archiveReader, files map[string]ManifestFile, dir string) (map[string]string, error) {
+	staged := make(map[string]string, len(files))
+	for {
+		name, r, err := archive.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(name, filesDir) {
+			return nil, fmt.Errorf("unexpected entry in bundle: %s", name)
+		}
+
+		p := "/" + strings.TrimPrefix(name, filesDir)
+		file, ok := files[p]
+		if !ok {
+			return nil, fmt.Errorf("%s is not listed in manifest", p)
+		}
+		if _, ok := staged[p]; ok {
+			return nil, fmt.Errorf("%s appears more than once in bundle", p)
+		}
+
+		tmpPath, err := stageFile(file, r, dir)
+		if err != nil {
+			return nil, err
+		}
+		staged[p] = tmpPath
+	}
+
+	for p := range files {
+		if _, ok := staged[p]; !ok {
+			return nil, fmt.Errorf("%s is listed in manifest but missing from bundle", p)
+		}
+	}
+	return staged, nil
+}
+
+// stageFile 将文件写入临时目录并校验长度和 SHA-256
+func stageFile(file ManifestFile, r io.Reader, dir string) (string, error) {
+	tmp, err := os.CreateTemp(dir, "file-*")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if err != nil {
+		return "", fmt.Errorf("read %s from bundle failed: %w", file.Path, err)
+	}
+	if n != file.Size {
+		return "", fmt.Errorf("%s: expected %d bytes, got %d", file.Path, file.Size, n)
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(actual, file.SHA256) {
+		return "", fmt.Errorf("%s: sha256 mismatch, expected %s, actual %s", file.Path, file.SHA256, actual)
+	}
+	return tmp.Name(), tmp.Close()
+}
+
+// putFile 将暂存的文件写入仓库
+func putFile(repo repository.Repository, file ManifestFile, tmpPath string) error {
+	tmp, err := os.Open(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	if err := repo.Put(file.Path, tmp, file.Size); err != nil {
+		return fmt.Errorf("write %s failed: %w", file.Path, err)
+	}
+	return nil
+}
+
+// rollback 删除本次导入的文件
+func rollback(repo repository.Repository, paths []string) error {
+	errs := []error{}
+	for _, p := range paths {
+		if err := repo.Delete(p); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("rollback %s failed: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readManifest 读取归档的第一个条目作为清单
+func readManifest(archive *archiveReader) (*Manifest, error) {
+	name, r, err := archive.next()
+	if err != nil {
+		return nil, fmt.Errorf("read manifest failed: %w", err)
+	}
+	if name != manifestName {
+		return nil, fmt.Errorf("first entry of bundle must be %s, got %s", manifestName, name)
+	}
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(io.LimitReader(r, maxManifestSize)).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest failed: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version: %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// archiveReader 按顺序读取 tar.gz 或 zip 归档中的文件
+type archiveReader struct {
+	file *os.File
+	gz   *gzip.Reader
+	tar  *tar.Reader
+	zip  *zip.Reader
+	idx  int
+	cur  io.ReadCloser
+}
+
+// openArchive 打开归档，根据文件头识别格式
+func openArchive(archivePath string) (*archiveReader, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read bundle failed: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &archiveReader{file: file, gz: gz, tar: tar.NewReader(gz)}, nil
+
+	case bytes.Equal(magic, []byte("PK\x03\x04")):
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		zr, err := zip.NewReader(file, stat.Size())
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &archiveReader{file: file, zip: zr}, nil
+
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported bundle format, expect %s or %s", FormatTarGz, FormatZip)
+	}
+}
+
+// next 返回下一个文件条目，没有更多条目时返回 io.EOF
+func (a *archiveReader) next() (string, io.Reader, error) {
+	if a.cur != nil {
+		a.cur.Close()
+		a.cur = nil
+	}
+
+	if a.zip != nil {
+		for a.idx < len(a.zip.File) {
+			f := a.zip.File[a.idx]
+			a.idx++
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return "", nil, err
+			}
+			a.cur = rc
+			return f.Name, rc, nil
+		}
+		return "", nil, io.EOF
+	}
+
+	for {
+		header, err := a.tar.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return header.Name, a.tar, nil
+		}
+		if header.Typeflag != tar.TypeDir {
+			return "", nil, fmt.Errorf("unsupported entry type in bundle: %s", header.Name)
+		}
+	}
+}
+
+func (a *archiveReader) Close() error {
+	if a.cur != nil {
+		a.cur.Close()
+	}
+	if a.gz != nil {
+		a.gz.Close()
+	}
+	return a.file.Close()
+}
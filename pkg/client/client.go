@@ -2,6 +2,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,8 +12,12 @@ import (
 
 // HTTPClient HTTP 客户端接口
 type HTTPClient interface {
-	// Get 发起 GET 请求，返回响应数据、状态码、响应头和错误
-	Get(url string) ([]byte, int, http.Header, error)
+	// Get 发起 GET 请求，返回响应体、状态码、响应头和错误
+	// 仅在状态码为 200 时返回响应体，调用方负责关闭
+	Get(url string) (io.ReadCloser, int, http.Header, error)
+
+	// GetWithHeader 携带额外请求头发起 GET 请求，用于 If-None-Match 等条件请求，返回值与 Get 相同
+	GetWithHeader(url string, header http.Header) (io.ReadCloser, int, http.Header, error)
 
 	// Download 下载文件到指定路径，支持断点续传
 	Download(url string, destPath string) (int, http.Header, error)
@@ -30,28 +35,45 @@ func NewDefaultHTTPClient(timeout time.Duration) *DefaultHTTPClient {
 		timeout = 30 * time.Second
 	}
 
+	// 响应体以流的方式返回给调用方，超时限制连接、等待响应头以及读取响应体时
+	// 每次等待数据的时间，不限制大文件的总传输时间
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
 	return &DefaultHTTPClient{
 		client: &http.Client{
-			Timeout: timeout,
+			Transport: transport,
 		},
 		timeout: timeout,
 	}
 }
 
 // Get 发起 GET 请求
-func (c *DefaultHTTPClient) Get(url string) ([]byte, int, http.Header, error) {
-	resp, err := c.client.Get(url)
+func (c *DefaultHTTPClient) Get(url string) (io.ReadCloser, int, http.Header, error) {
+	return c.GetWithHeader(url, nil)
+}
+
+// GetWithHeader 携带额外请求头发起 GET 请求
+func (c *DefaultHTTPClient) GetWithHeader(url string, header http.Header) (io.ReadCloser, int, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("HTTP GET failed: %w", err)
+		return nil, 0, nil, fmt.Errorf("create request failed: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, resp.StatusCode, resp.Header, fmt.Errorf("read response body failed: %w", err)
+		return nil, 0, nil, fmt.Errorf("HTTP GET failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, resp.StatusCode, resp.Header, nil
 	}
 
-	return data, resp.StatusCode, resp.Header, nil
+	return resp.Body, resp.StatusCode, resp.Header, nil
 }
 
 // Download 下载文件到指定路径，支持断点续传
@@ -74,7 +96,7 @@ func (c *DefaultHTTPClient) Download(url string, destPath string) (int, http.Hea
 	}
 
 	// 发起请求
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return 0, nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -117,3 +139,49 @@ func (c *DefaultHTTPClient) Download(url string, destPath string) (int, http.Hea
 
 	return resp.StatusCode, resp.Header, nil
 }
+
+// do 发起请求，响应体在 timeout 内没有收到数据时取消请求，避免上游在传输中途停止发送时
+// 一直占用连接以及调用方持有的锁
+func (c *DefaultHTTPClient) do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	timer := time.AfterFunc(c.timeout, cancel)
+	timer.Stop()
+	resp.Body = &idleTimeoutBody{
+		body:    resp.Body,
+		timeout: c.timeout,
+		timer:   timer,
+		cancel:  cancel,
+	}
+	return resp, nil
+}
+
+// idleTimeoutBody 每次读取前重置计时，只计算等待上游数据的时间，调用方处理数据的时间不计入
+type idleTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.body.Read(p)
+	stopped := b.timer.Stop()
+	if err != nil && err != io.EOF && !stopped {
+		return n, fmt.Errorf("read response body: no data received for %s: %w", b.timeout, err)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		io.WriteString(w, "12345")
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		io.WriteString(w, "67890")
+	}))
+	defer srv.Close()
+
+	c := NewDefaultHTTPClient(100 * time.Millisecond)
+
+	// 上游在传输中途停止发送
+	body, status, _, err := c.Get(srv.URL + "/stall")
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Get = %d, %v", status, err)
+	}
+	start := time.Now()
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Fatalf("ReadAll = %q, %v, want idle timeout", data, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("idle timeout took %s", elapsed)
+	}
+
+	// 调用方处理数据的时间不计入
+	body, _, _, err = c.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	buf := make([]byte, 5)
+	received := ""
+	for {
+		n, err := body.Read(buf)
+		received += string(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read after %q: %v", received, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	if received != "1234567890" {
+		t.Errorf("received %q", received)
+	}
+}
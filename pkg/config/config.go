@@ -2,6 +2,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -11,6 +13,10 @@ type Config struct {
 	Port            string        `yaml:"port" default:"8880"`
 	Context         string        `yaml:"context" default:"maven"`
 	LocalRepository string        `yaml:"localRepository" default:"."`
+	Storage         *Storage      `yaml:"storage"`
+	Scrub           *Scrub        `yaml:"scrub"`
+	Index           *Index        `yaml:"index"`
+	Watch           *Watch        `yaml:"watch"`
 	User            []*User       `yaml:"user"`
 	Repository      []*Repository `yaml:"repository"`
 	Logging         *Logging      `yaml:"logging"`
@@ -33,6 +39,83 @@ type Repository struct {
 	Type    string            `yaml:"type" default:"hosted"`
 	Members []string          `yaml:"members"`
 	Routes  map[string]string `yaml:"routes"`
+
+	Storage          *Storage       `yaml:"storage"`                         // 仓库独立的存储后端，为空时使用全局存储，target 作为其中的目录
+	GenerateMetadata *bool          `yaml:"generateMetadata" default:"true"` // hosted 仓库由服务端维护 maven-metadata.xml
+	MetadataCache    *MetadataCache `yaml:"metadataCache"`
+	NotFoundCache    *NotFoundCache `yaml:"notFoundCache"`
+	MemoryCache      *MemoryCache   `yaml:"memoryCache"`
+	Quota            *Quota         `yaml:"quota"`
+	Encryption       *Encryption    `yaml:"encryption"`
+	Tiered           *Tiered        `yaml:"tiered"`
+	CacheLimit       *CacheLimit    `yaml:"cacheLimit"`
+}
+
+// MetadataCache proxy 仓库缓存上游的 maven-metadata.xml，TTL 内直接使用缓存，
+// 过期后向上游发送条件请求重新验证，上游不可用时继续使用过期的缓存
+type MetadataCache struct {
+	TTL time.Duration `yaml:"ttl" default:"30m"`
+}
+
+// NotFoundCache proxy 仓库缓存所有镜像都返回 404 的路径，TTL 内不再访问上游，
+// 记录可以通过 /_admin/notfound/clear 清除
+type NotFoundCache struct {
+	TTL             time.Duration `yaml:"ttl" default:"10m"`
+	MaxEntries      int           `yaml:"maxEntries" default:"10000"`
+	ExcludeMetadata *bool         `yaml:"excludeMetadata" default:"true"` // 不缓存 maven-metadata.xml 的 404
+}
+
+// CacheLimit proxy 仓库缓存大小上限，超出时按最后访问时间淘汰构件及其校验和文件
+type CacheLimit struct {
+	MaxSize      ByteSize      `yaml:"maxSize"`                   // 缓存大小上限
+	LowWatermark int           `yaml:"lowWatermark" default:"90"` // 每次淘汰到上限的该百分比以下
+	Interval     time.Duration `yaml:"interval" default:"10m"`    // 定期重新统计缓存大小的周期
+}
+
+// Tiered 冷热分层存储配置，仓库的常规存储作为热层，
+// 超过 migrateAfter 未访问的文件迁移到 cold 指定的冷层
+type Tiered struct {
+	Cold         *Storage      `yaml:"cold"`
+	MigrateAfter time.Duration `yaml:"migrateAfter" default:"720h"` // 默认 30 天
+	Interval     time.Duration `yaml:"interval" default:"1h"`       // 后台迁移周期
+	Promote      *bool         `yaml:"promote" default:"true"`      // 冷层文件被访问时移回热层
+}
+
+// Encryption 仓库静态加密配置，密钥格式为 <keyID>:<base64 编码的 32 字节密钥>，
+// 每行或每个逗号分隔项一个，轮换密钥时添加新密钥并修改 activeKey，旧密钥保留用于读取
+type Encryption struct {
+	KeyFile   string `yaml:"keyFile"`   // 密钥文件路径
+	KeyEnv    string `yaml:"keyEnv"`    // 保存密钥的环境变量名
+	ActiveKey string `yaml:"activeKey"` // 加密新文件使用的密钥 ID，默认为第一个密钥
+}
+
+// Quota 仓库存储配额，超出时上传返回 507
+type Quota struct {
+	MaxSize   ByteSize `yaml:"maxSize"`                // 最大字节数，0 表示不限制
+	MaxFiles  int64    `yaml:"maxFiles"`               // 最大文件数，0 表示不限制
+	SoftLimit int      `yaml:"softLimit" default:"80"` // 使用量超过该百分比时写告警日志
+}
+
+// MemoryCache 仓库小文件内存缓存配置
+type MemoryCache struct {
+	MaxSize     ByteSize `yaml:"maxSize" default:"67108864"`    // 缓存总大小上限，默认 64MB
+	MaxFileSize ByteSize `yaml:"maxFileSize" default:"1048576"` // 单个文件大小上限，默认 1MB
+}
+
+// Scrub 后台完整性校验配置，定期重新计算构件摘要并与校验和文件比对
+type Scrub struct {
+	Interval   time.Duration `yaml:"interval" default:"24h"` // 校验周期，0 表示只通过管理接口触发
+	Quarantine string        `yaml:"quarantine"`             // 隔离目录，损坏的构件移到 <目录>/<仓库 ID>/ 下，为空时只报告
+}
+
+// Watch 监视 hosted 仓库目录，处理绕过 HTTP 直接放入的文件
+type Watch struct {
+	Interval time.Duration `yaml:"interval" default:"30s"`
+}
+
+// Index 持久化文件索引配置
+type Index struct {
+	Path string `yaml:"path" default:"index.db"` // 索引文件路径
 }
 
 // Logging 日志配置
@@ -40,3 +123,29 @@ type Logging struct {
 	Path  string       `yaml:"path" default:""`
 	Level logrus.Level `yaml:"level" default:"debug"`
 }
+
+// Storage 存储后端配置
+type Storage struct {
+	Type    string     `yaml:"type" default:"filesystem"` // filesystem、sharded、blob 或 s3
+	Path    string     `yaml:"path"`                      // filesystem 和 blob 的根目录，默认为 localRepository
+	Volumes []string   `yaml:"volumes"`                   // sharded 的各个卷目录，新增卷后启动时在后台重新平衡
+	S3      *S3Storage `yaml:"s3"`
+	Lock    *FileLock  `yaml:"lock"` // 多个实例共享同一个 filesystem 或 sharded 目录（如 NFS）时启用
+}
+
+// FileLock 锁文件配置，写入和删除前在文件旁创建 .<name>.lock，持有期间定期续约
+type FileLock struct {
+	TTL     time.Duration `yaml:"ttl" default:"30s"`     // 租约有效期，超过该时间没有续约的锁视为失效，应大于实例之间的时钟偏差
+	Timeout time.Duration `yaml:"timeout" default:"60s"` // 等待其他实例释放锁的最长时间
+}
+
+// S3Storage S3 兼容对象存储配置
+type S3Storage struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region" default:"us-east-1"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"`
+	AccessKey string `yaml:"accessKey"` // 为空时读取 AWS_ACCESS_KEY_ID
+	SecretKey string `yaml:"secretKey"` // 为空时读取 AWS_SECRET_ACCESS_KEY
+	PathStyle bool   `yaml:"pathStyle" default:"false"`
+}
@@ -18,7 +18,7 @@ type Loader struct {
 	configPath string
 }
 
-// NewLoader 创建配置加载器
+// NewLoader 创建配置加载器，配置文件路径由命令行参数 -c 指定
 func NewLoader() *Loader {
 	loader := &Loader{}
 	flag.StringVar(&loader.configPath, "c", "config.yaml", "配置文件路径")
@@ -26,6 +26,11 @@ func NewLoader() *Loader {
 	return loader
 }
 
+// NewFileLoader 创建加载指定配置文件的加载器
+func NewFileLoader(configPath string) *Loader {
+	return &Loader{configPath: configPath}
+}
+
 // Load 加载配置文件
 func (l *Loader) Load() (*Config, error) {
 	log.SetLevel(logrus.InfoLevel)
@@ -53,6 +58,14 @@ func (l *Loader) Load() (*Config, error) {
 		return nil, fmt.Errorf("set defaults failed: %w", err)
 	}
 
+	// 未配置存储后端时使用 localRepository 目录
+	if cfg.Storage == nil {
+		cfg.Storage = &Storage{Type: "filesystem"}
+	}
+	if cfg.Storage.Type != "s3" && cfg.Storage.Path == "" {
+		cfg.Storage.Path = cfg.LocalRepository
+	}
+
 	// 验证和预处理仓库配置
 	if err := l.validateRepositories(cfg); err != nil {
 		return nil, err
@@ -78,6 +91,11 @@ func (l *Loader) validateRepositories(cfg *Config) error {
 			repo.Target = repo.Id
 		}
 
+		// 仓库独立的存储未配置目录时同样使用 localRepository
+		if repo.Storage != nil && repo.Storage.Type != "s3" && repo.Storage.Path == "" {
+			repo.Storage.Path = cfg.LocalRepository
+		}
+
 		// 验证 group 类型仓库
 		if repo.Type == "group" {
 			if len(repo.Members) == 0 {
@@ -0,0 +1,66 @@
+// pkg/event/event.go
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Type 事件类型
+type Type string
+
+const (
+	Deployed Type = "deployed" // 文件被上传或放入仓库
+	Deleted  Type = "deleted"  // 文件或目录被删除
+)
+
+// 事件来源
+const (
+	SourceHTTP    = "http"    // 通过 HTTP 上传或删除
+	SourceWatcher = "watcher" // 直接放入仓库目录后被发现
+)
+
+// Event 仓库内容变更事件
+type Event struct {
+	Type       Type      `json:"type"`
+	Repository string    `json:"repository"`
+	Path       string    `json:"path"`
+	Source     string    `json:"source"`
+	Time       time.Time `json:"time"`
+}
+
+// Handler 事件处理函数，在发布者的 goroutine 中同步调用
+type Handler func(Event)
+
+// Bus 进程内的事件总线
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe 订阅所有事件
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish 发布事件，未设置时间时使用当前时间
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
@@ -0,0 +1,73 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+)
+
+// snapshotTimestamp 快照构件文件名中的时间戳版本，如 20240101.120000-1
+var snapshotTimestamp = regexp.MustCompile(`^\d{8}\.\d{6}-\d+`)
+
+// Coordinates Maven 坐标
+type Coordinates struct {
+	GroupId    string `json:"groupId,omitempty"`
+	ArtifactId string `json:"artifactId,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Classifier string `json:"classifier,omitempty"`
+	Extension  string `json:"extension,omitempty"`
+}
+
+// ParseCoordinates 按 Maven 仓库布局从路径解析坐标，
+// 路径形如 /org/example/demo/1.0/demo-1.0-sources.jar，不符合布局时返回 false
+func ParseCoordinates(p string) (Coordinates, bool) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) < 4 {
+		return Coordinates{}, false
+	}
+
+	n := len(segments)
+	file, version, artifactId := segments[n-1], segments[n-2], segments[n-3]
+
+	// 快照版本的文件名可能使用时间戳代替 SNAPSHOT
+	rest := ""
+	if base := strings.TrimSuffix(version, "SNAPSHOT"); base != version {
+		prefix := artifactId + "-" + base
+		if !strings.HasPrefix(file, prefix) {
+			return Coordinates{}, false
+		}
+		rest = file[len(prefix):]
+		if strings.HasPrefix(rest, "SNAPSHOT") {
+			rest = rest[len("SNAPSHOT"):]
+		} else if loc := snapshotTimestamp.FindStringIndex(rest); loc != nil {
+			rest = rest[loc[1]:]
+		} else {
+			return Coordinates{}, false
+		}
+	} else {
+		prefix := artifactId + "-" + version
+		if !strings.HasPrefix(file, prefix) {
+			return Coordinates{}, false
+		}
+		rest = file[len(prefix):]
+	}
+
+	coords := Coordinates{
+		GroupId:    strings.Join(segments[:n-3], "."),
+		ArtifactId: artifactId,
+		Version:    version,
+	}
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+		dot := strings.Index(rest, ".")
+		if dot <= 0 {
+			return Coordinates{}, false
+		}
+		coords.Classifier = rest[:dot]
+		rest = rest[dot:]
+	}
+	if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+		return Coordinates{}, false
+	}
+	coords.Extension = rest[1:]
+	return coords, true
+}
@@ -0,0 +1,469 @@
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"maven-proxy/pkg/storage"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetLevel(logrus.InfoLevel)
+	log.SetFormatter(&logrus.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+	})
+}
+
+// repositoriesBucket 根 bucket，其下每个仓库一个子 bucket，键为文件路径
+var repositoriesBucket = []byte("repositories")
+
+// rebuiltBucket 记录完成过重建的仓库，值为完成时间。重建开始时先删除记录，中断的重建不会留下记录
+var rebuiltBucket = []byte("rebuilt")
+
+// rebuildBatchSize 重建索引时每个事务写入的条目数
+const rebuildBatchSize = 500
+
+// Entry 索引中的文件记录
+type Entry struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Coordinates
+	Size      int64     `json:"size"`
+	SHA1      string    `json:"sha1,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"`
+	ModTime   time.Time `json:"modTime"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+// Query 搜索条件，为空的字段不参与过滤
+type Query struct {
+	Repository string
+	GroupId    string
+	ArtifactId string
+	Version    string
+	Classifier string
+	Extension  string
+	Name       string // 路径中包含的文本，不区分大小写
+	Limit      int    // 返回的最大条目数，0 表示不限制
+}
+
+// Stats 单个仓库的统计信息
+type Stats struct {
+	Files     int64 `json:"files"`
+	Bytes     int64 `json:"bytes"`
+	Artifacts int64 `json:"artifacts"` // 不同 groupId:artifactId 的数量
+	Versions  int64 `json:"versions"`  // 不同 groupId:artifactId:version 的数量
+}
+
+// Index 基于 bbolt 的持久化文件索引
+type Index struct {
+	db *bolt.DB
+}
+
+// Open 打开索引文件，不存在时创建
+func Open(path string) (*Index, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("index %s is locked by another process", path)
+		}
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(repositoriesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(rebuiltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Index{db: db}, nil
+}
+
+// Close 关闭索引
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// Put 添加或更新文件记录
+func (i *Index) Put(entry *Entry) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		return putEntry(tx, entry)
+	})
+}
+
+// Update 根据存储中的文件添加或更新记录
+func (i *Index) Update(repo, p string, s storage.Storage) error {
+	entry, err := newEntry(repo, p, s)
+	if err != nil {
+		return err
+	}
+	return i.Put(entry)
+}
+
+// Get 返回文件记录，不存在时返回 storage.ErrNotFound
+func (i *Index) Get(repo, p string) (*Entry, error) {
+	key := indexKey(p)
+	var entry *Entry
+	err := i.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket).Bucket([]byte(repo))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		entry = &Entry{}
+		return json.Unmarshal(data, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s", storage.ErrNotFound, key)
+	}
+	return entry, nil
+}
+
+// Delete 删除文件记录，路径为目录时删除目录下的所有记录
+func (i *Index) Delete(repo, p string) error {
+	key := indexKey(p)
+	return i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket).Bucket([]byte(repo))
+		if bucket == nil {
+			return nil
+		}
+		if err := bucket.Delete([]byte(key)); err != nil {
+			return err
+		}
+
+		prefix := []byte(strings.TrimSuffix(key, "/") + "/")
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = cursor.Seek(prefix) {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Complete 返回仓库的索引是否完整，即完成过重建且当前没有在重建
+func (i *Index) Complete(repo string) bool {
+	complete := false
+	i.db.View(func(tx *bolt.Tx) error {
+		complete = tx.Bucket(rebuiltBucket).Get([]byte(repo)) != nil
+		return nil
+	})
+	return complete
+}
+
+// List 返回目录下的文件和子目录，按名称排序。子目录由更深层的文件记录合成，不带修改时间；
+// 目录下没有记录时返回 storage.ErrNotFound
+func (i *Index) List(repo, p string) ([]storage.FileInfo, error) {
+	prefix := []byte(strings.TrimSuffix(indexKey(p), "/") + "/")
+	entries := []storage.FileInfo{}
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket).Bucket([]byte(repo))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); {
+			name := string(k[len(prefix):])
+			if slash := strings.IndexByte(name, '/'); slash >= 0 {
+				// 子目录只记录一次，'0' 紧跟在 '/' 之后，定位到它跳过子目录下的其余记录
+				name = name[:slash]
+				entries = append(entries, storage.FileInfo{Name: name, IsDir: true})
+				k, v = cursor.Seek(append(append([]byte(nil), prefix...), name+"0"...))
+				continue
+			}
+
+			entry := Entry{}
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, storage.FileInfo{Name: name, Size: entry.Size, ModTime: entry.ModTime})
+			k, v = cursor.Next()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", storage.ErrNotFound, indexKey(p))
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Name < entries[b].Name
+	})
+	return entries, nil
+}
+
+// Search 按条件搜索文件记录，结果按仓库和路径排序
+func (i *Index) Search(q Query) ([]Entry, error) {
+	name := strings.ToLower(q.Name)
+	entries := []Entry{}
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return i.forEachRepository(tx, q.Repository, func(repo string, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(k, v []byte) error {
+				if q.Limit > 0 && len(entries) >= q.Limit {
+					return errLimitReached
+				}
+				if name != "" && !strings.Contains(strings.ToLower(string(k)), name) {
+					return nil
+				}
+
+				entry := Entry{}
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return err
+				}
+				if q.matches(&entry) {
+					entries = append(entries, entry)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil && !errors.Is(err, errLimitReached) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Stats 返回各仓库的统计信息
+func (i *Index) Stats() (map[string]Stats, error) {
+	result := make(map[string]Stats)
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		return i.forEachRepository(tx, "", func(repo string, bucket *bolt.Bucket) error {
+			stats := Stats{}
+			artifacts := make(map[string]bool)
+			versions := make(map[string]bool)
+
+			err := bucket.ForEach(func(k, v []byte) error {
+				entry := Entry{}
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return err
+				}
+				stats.Files++
+				stats.Bytes += entry.Size
+				if entry.ArtifactId != "" {
+					ga := entry.GroupId + ":" + entry.ArtifactId
+					artifacts[ga] = true
+					versions[ga+":"+entry.Version] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			stats.Artifacts = int64(len(artifacts))
+			stats.Versions = int64(len(versions))
+			result[repo] = stats
+			return nil
+		})
+	})
+	return result, err
+}
+
+// Rebuild 遍历存储重建仓库的索引，返回索引的文件数
+//
+// 重建期间的写入和删除仍会更新索引，因此不先清空，
+// 而是在遍历完成后删除开始重建前就存在、但本次没有遍历到的记录
+func (i *Index) Rebuild(repo string, s storage.Storage) (int, error) {
+	if err := i.markRebuilt(repo, false); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	count := 0
+	batch := make([]*Entry, 0, rebuildBatchSize)
+
+	flush := func() error {
+		err := i.db.Update(func(tx *bolt.Tx) error {
+			for _, entry := range batch {
+				if err := putEntry(tx, entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return err
+	}
+
+	err := storage.Walk(s, "/", func(p string, _ storage.FileInfo) error {
+		entry, err := newEntry(repo, p, s)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		batch = append(batch, entry)
+		count++
+		if len(batch) == rebuildBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil && s.Exists("/") {
+		return count, err
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+
+	// 删除已不存在的文件记录
+	err = i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket).Bucket([]byte(repo))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; {
+			entry := Entry{}
+			if err := json.Unmarshal(v, &entry); err != nil || entry.IndexedAt.Before(start) {
+				// 删除后游标位置不可靠，重新定位到下一条
+				key := append([]byte(nil), k...)
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				k, v = cursor.Seek(key)
+				continue
+			}
+			k, v = cursor.Next()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, i.markRebuilt(repo, true)
+}
+
+// markRebuilt 记录或清除仓库完成重建的标记
+func (i *Index) markRebuilt(repo string, done bool) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rebuiltBucket)
+		if !done {
+			return bucket.Delete([]byte(repo))
+		}
+		return bucket.Put([]byte(repo), []byte(time.Now().Format(time.RFC3339)))
+	})
+}
+
+// errLimitReached 搜索结果达到上限时终止遍历
+var errLimitReached = errors.New("limit reached")
+
+func (i *Index) forEachRepository(tx *bolt.Tx, repo string, fn func(repo string, bucket *bolt.Bucket) error) error {
+	root := tx.Bucket(repositoriesBucket)
+	if repo != "" {
+		bucket := root.Bucket([]byte(repo))
+		if bucket == nil {
+			return nil
+		}
+		return fn(repo, bucket)
+	}
+
+	return root.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return fn(string(k), root.Bucket(k))
+	})
+}
+
+func (q *Query) matches(entry *Entry) bool {
+	return (q.GroupId == "" || q.GroupId == entry.GroupId) &&
+		(q.ArtifactId == "" || q.ArtifactId == entry.ArtifactId) &&
+		(q.Version == "" || q.Version == entry.Version) &&
+		(q.Classifier == "" || q.Classifier == entry.Classifier) &&
+		(q.Extension == "" || q.Extension == entry.Extension)
+}
+
+func putEntry(tx *bolt.Tx, entry *Entry) error {
+	bucket, err := tx.Bucket(repositoriesBucket).CreateBucketIfNotExists([]byte(entry.Repository))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(entry.Path), data)
+}
+
+// newEntry 根据存储中的文件生成记录，存储没有记录摘要时读取文件计算
+func newEntry(repo, p string, s storage.Storage) (*Entry, error) {
+	info, err := s.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		Repository: repo,
+		Path:       indexKey(p),
+		Size:       info.Size,
+		SHA1:       info.SHA1,
+		SHA256:     info.SHA256,
+		ModTime:    info.ModTime,
+		IndexedAt:  time.Now(),
+	}
+	entry.Coordinates, _ = ParseCoordinates(entry.Path)
+
+	if entry.SHA1 == "" || entry.SHA256 == "" {
+		content, err := s.Read(p)
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		sha1Hasher, sha256Hasher := sha1.New(), sha256.New()
+		if _, err := io.Copy(io.MultiWriter(sha1Hasher, sha256Hasher), content); err != nil {
+			return nil, err
+		}
+		entry.SHA1 = hex.EncodeToString(sha1Hasher.Sum(nil))
+		entry.SHA256 = hex.EncodeToString(sha256Hasher.Sum(nil))
+	}
+	return entry, nil
+}
+
+// indexKey 规范化路径作为索引键
+func indexKey(p string) string {
+	if cleaned, err := storage.CleanPath(p); err == nil {
+		p = cleaned
+	}
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
@@ -0,0 +1,117 @@
+package index
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"maven-proxy/pkg/storage"
+)
+
+// IndexedStorage 在写入和删除时同步更新索引的存储包装器
+// 索引更新失败不影响存储操作本身，可以通过重建索引修复
+type IndexedStorage struct {
+	base  storage.Storage
+	index *Index
+	repo  string
+}
+
+// NewIndexedStorage 创建索引包装器
+func NewIndexedStorage(base storage.Storage, index *Index, repo string) *IndexedStorage {
+	return &IndexedStorage{
+		base:  base,
+		index: index,
+		repo:  repo,
+	}
+}
+
+// Rebuild 从底层存储重建仓库的索引，返回索引的文件数
+func (s *IndexedStorage) Rebuild() (int, error) {
+	return s.index.Rebuild(s.repo, s.base)
+}
+
+func (s *IndexedStorage) Read(path string) (*storage.Content, error) {
+	return s.base.Read(path)
+}
+
+// Write 写入的同时计算摘要，写入成功后更新索引
+func (s *IndexedStorage) Write(path string, r io.Reader, size int64) error {
+	sha1Hasher, sha256Hasher := sha1.New(), sha256.New()
+	counter := &countWriter{}
+	if err := s.base.Write(path, io.TeeReader(r, io.MultiWriter(sha1Hasher, sha256Hasher, counter)), size); err != nil {
+		return err
+	}
+
+	entry := &Entry{
+		Repository: s.repo,
+		Path:       indexKey(path),
+		Size:       counter.n,
+		SHA1:       hex.EncodeToString(sha1Hasher.Sum(nil)),
+		SHA256:     hex.EncodeToString(sha256Hasher.Sum(nil)),
+		ModTime:    time.Now(),
+		IndexedAt:  time.Now(),
+	}
+	entry.Coordinates, _ = ParseCoordinates(entry.Path)
+	if info, err := s.base.Stat(path); err == nil {
+		entry.ModTime = info.ModTime
+	}
+
+	if err := s.index.Put(entry); err != nil {
+		log.Warnf("index %s%s failed: %v", s.repo, entry.Path, err)
+	}
+	return nil
+}
+
+// List 索引完整时从索引列出目录，不遍历底层存储；
+// 索引正在重建、尚未建立或没有该目录的记录时回退到底层存储
+func (s *IndexedStorage) List(path string) ([]storage.FileInfo, error) {
+	if s.index.Complete(s.repo) {
+		entries, err := s.index.List(s.repo, path)
+		if err == nil {
+			return entries, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Warnf("list %s%s from index failed: %v", s.repo, indexKey(path), err)
+		}
+	}
+	return s.base.List(path)
+}
+
+func (s *IndexedStorage) Exists(path string) bool {
+	return s.base.Exists(path)
+}
+
+func (s *IndexedStorage) Stat(path string) (*storage.FileInfo, error) {
+	return s.base.Stat(path)
+}
+
+func (s *IndexedStorage) Delete(path string) error {
+	if err := s.base.Delete(path); err != nil {
+		return err
+	}
+	if err := s.index.Delete(s.repo, path); err != nil {
+		log.Warnf("remove %s%s from index failed: %v", s.repo, indexKey(path), err)
+	}
+	return nil
+}
+
+// countWriter 统计写入的字节数
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func (s *IndexedStorage) Touch(path string) {
+	storage.Touch(s.base, path)
+}
+
+func (s *IndexedStorage) LockPath(path string) (func(), error) {
+	return storage.LockPath(s.base, path)
+}
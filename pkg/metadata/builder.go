@@ -0,0 +1,282 @@
+package metadata
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"maven-proxy/pkg/storage"
+)
+
+// snapshotTimestamp 快照文件名中的时间戳和构建号，如 20240101.120000-3
+var snapshotTimestamp = regexp.MustCompile(`^(\d{8}\.\d{6})-(\d+)`)
+
+// SnapshotFile 快照版本目录中的构件文件
+type SnapshotFile struct {
+	Name        string
+	Classifier  string
+	Extension   string
+	Value       string // 文件版本，如 1.0-20240101.120000-3，非唯一快照为 1.0-SNAPSHOT
+	Timestamp   string // 非唯一快照为空
+	BuildNumber int
+	Updated     time.Time
+}
+
+// newerThan 判断是否比另一个文件更新，时间戳版本优先于非唯一快照
+func (f *SnapshotFile) newerThan(other *SnapshotFile) bool {
+	if (f.Timestamp != "") != (other.Timestamp != "") {
+		return f.Timestamp != ""
+	}
+	if f.Timestamp != other.Timestamp {
+		return f.Timestamp > other.Timestamp
+	}
+	if f.BuildNumber != other.BuildNumber {
+		return f.BuildNumber > other.BuildNumber
+	}
+	return f.Updated.After(other.Updated)
+}
+
+// ParseSnapshotFile 解析快照版本目录中的文件名，校验和文件和不属于该版本的文件返回 false
+func ParseSnapshotFile(artifactId, version, name string, modTime time.Time) (*SnapshotFile, bool) {
+	base := strings.TrimSuffix(version, "SNAPSHOT")
+	prefix := artifactId + "-" + base
+	if base == version || !strings.HasPrefix(name, prefix) || isChecksumFile(name) {
+		return nil, false
+	}
+
+	file := &SnapshotFile{Name: name, Updated: modTime}
+	rest := name[len(prefix):]
+	if strings.HasPrefix(rest, "SNAPSHOT") {
+		file.Value = version
+		rest = rest[len("SNAPSHOT"):]
+	} else if match := snapshotTimestamp.FindStringSubmatch(rest); match != nil {
+		timestamp, err := time.Parse(timestampFormat, match[1])
+		if err != nil {
+			return nil, false
+		}
+		file.Value = base + match[0]
+		file.Timestamp = match[1]
+		file.BuildNumber, _ = strconv.Atoi(match[2])
+		file.Updated = timestamp
+		rest = rest[len(match[0]):]
+	} else {
+		return nil, false
+	}
+
+	if strings.HasPrefix(rest, "-") {
+		dot := strings.Index(rest, ".")
+		if dot <= 1 {
+			return nil, false
+		}
+		file.Classifier = rest[1:dot]
+		rest = rest[dot:]
+	}
+	if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+		return nil, false
+	}
+	file.Extension = rest[1:]
+	return file, true
+}
+
+// ListSnapshotFiles 列出快照版本目录中的构件文件
+func ListSnapshotFiles(s storage.Storage, versionDir string) ([]*SnapshotFile, error) {
+	_, artifactId, version, ok := splitVersionDir(versionDir)
+	if !ok || !IsSnapshot(version) {
+		return nil, nil
+	}
+
+	entries, err := s.List(versionDir)
+	if err != nil {
+		return nil, err
+	}
+	files := []*SnapshotFile{}
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		if file, ok := ParseSnapshotFile(artifactId, version, entry.Name, entry.ModTime); ok {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// BuildSnapshot 根据快照版本目录中的文件生成版本级别的元数据，目录中没有构件时返回 nil
+func BuildSnapshot(s storage.Storage, versionDir string) (*Metadata, error) {
+	groupId, artifactId, version, ok := splitVersionDir(versionDir)
+	if !ok || !IsSnapshot(version) {
+		return nil, nil
+	}
+
+	files, err := ListSnapshotFiles(s, versionDir)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	// 每个 classifier 和扩展名取最新的文件
+	latest := make(map[string]*SnapshotFile)
+	var newest *SnapshotFile
+	for _, file := range files {
+		key := file.Classifier + ":" + file.Extension
+		if current, ok := latest[key]; !ok || file.newerThan(current) {
+			latest[key] = file
+		}
+		if file.Timestamp != "" && (newest == nil || file.newerThan(newest)) {
+			newest = file
+		}
+	}
+
+	versioning := &Versioning{LastUpdated: formatLastUpdated(time.Now())}
+	if newest != nil {
+		versioning.Snapshot = &Snapshot{Timestamp: newest.Timestamp, BuildNumber: newest.BuildNumber}
+	}
+	for _, file := range latest {
+		versioning.SnapshotVersions = append(versioning.SnapshotVersions, SnapshotVersion{
+			Classifier: file.Classifier,
+			Extension:  file.Extension,
+			Value:      file.Value,
+			Updated:    formatLastUpdated(file.Updated),
+		})
+	}
+	sort.Slice(versioning.SnapshotVersions, func(i, j int) bool {
+		a, b := versioning.SnapshotVersions[i], versioning.SnapshotVersions[j]
+		if a.Extension != b.Extension {
+			return a.Extension < b.Extension
+		}
+		return a.Classifier < b.Classifier
+	})
+
+	return &Metadata{
+		ModelVersion: "1.1.0",
+		GroupId:      groupId,
+		ArtifactId:   artifactId,
+		Version:      version,
+		Versioning:   versioning,
+	}, nil
+}
+
+// BuildArtifact 根据构件目录下的版本目录生成 artifact 级别的元数据，没有任何版本时返回 nil
+func BuildArtifact(s storage.Storage, artifactDir string) (*Metadata, error) {
+	groupId, artifactId, ok := splitArtifactDir(artifactDir)
+	if !ok {
+		return nil, nil
+	}
+
+	entries, err := s.List(artifactDir)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir {
+			continue
+		}
+		has, err := hasArtifactFiles(s, path.Join(artifactDir, entry.Name), artifactId)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			versions = append(versions, entry.Name)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	versioning := &Versioning{
+		Latest:      versions[len(versions)-1],
+		Versions:    versions,
+		LastUpdated: formatLastUpdated(time.Now()),
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !IsSnapshot(versions[i]) {
+			versioning.Release = versions[i]
+			break
+		}
+	}
+
+	return &Metadata{
+		GroupId:    groupId,
+		ArtifactId: artifactId,
+		Versioning: versioning,
+	}, nil
+}
+
+// IsArtifactDir 判断目录下是否有包含构件文件的版本目录
+func IsArtifactDir(s storage.Storage, dir string) bool {
+	m, err := BuildArtifact(s, dir)
+	return err == nil && m != nil
+}
+
+// ArtifactPaths 从构件文件路径得到版本目录和构件目录，路径不符合 Maven 仓库布局时返回 false
+func ArtifactPaths(p string) (versionDir, artifactDir string, ok bool) {
+	versionDir = path.Dir(p)
+	artifactDir = path.Dir(versionDir)
+	if _, artifactId, _, ok := splitVersionDir(versionDir); !ok || !strings.HasPrefix(path.Base(p), artifactId+"-") {
+		return "", "", false
+	}
+	return versionDir, artifactDir, true
+}
+
+// hasArtifactFiles 判断版本目录下是否有构件文件
+func hasArtifactFiles(s storage.Storage, versionDir, artifactId string) (bool, error) {
+	entries, err := s.List(versionDir)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir && strings.HasPrefix(entry.Name, artifactId+"-") && !isChecksumFile(entry.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// splitArtifactDir 从构件目录得到 groupId 和 artifactId
+func splitArtifactDir(dir string) (groupId, artifactId string, ok bool) {
+	segments := strings.Split(strings.Trim(dir, "/"), "/")
+	if len(segments) < 2 || segments[0] == "" {
+		return "", "", false
+	}
+	n := len(segments)
+	return strings.Join(segments[:n-1], "."), segments[n-1], true
+}
+
+// splitVersionDir 从版本目录得到坐标
+func splitVersionDir(dir string) (groupId, artifactId, version string, ok bool) {
+	groupId, artifactId, ok = splitArtifactDir(path.Dir(dir))
+	if !ok {
+		return "", "", "", false
+	}
+	return groupId, artifactId, path.Base(dir), true
+}
+
+// isChecksumFile 判断是否为校验和文件
+func isChecksumFile(name string) bool {
+	for _, hashType := range ChecksumTypes {
+		if strings.HasSuffix(name, "."+hashType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import "sort"
+
+// Merge 合并多个仓库中同一路径的元数据，用于 group 仓库
+//
+// 版本列表取并集并按版本号排序，latest、release 取各自最大的版本，快照版本取时间戳最新的构建，
+// 每个 classifier 和扩展名取 updated 最新的文件，插件按前缀去重，先出现的优先
+func Merge(items []*Metadata) *Metadata {
+	if len(items) == 0 {
+		return nil
+	}
+
+	merged := &Metadata{}
+	var versioning *Versioning
+	versionSet := make(map[string]bool)
+	snapshotVersions := make(map[string]SnapshotVersion)
+	pluginSet := make(map[string]bool)
+
+	for _, m := range items {
+		if merged.GroupId == "" {
+			merged.GroupId = m.GroupId
+		}
+		if merged.ArtifactId == "" {
+			merged.ArtifactId = m.ArtifactId
+		}
+		if merged.Version == "" {
+			merged.Version = m.Version
+		}
+		if m.ModelVersion > merged.ModelVersion {
+			merged.ModelVersion = m.ModelVersion
+		}
+		for _, plugin := range m.Plugins {
+			if !pluginSet[plugin.Prefix] {
+				pluginSet[plugin.Prefix] = true
+				merged.Plugins = append(merged.Plugins, plugin)
+			}
+		}
+
+		v := m.Versioning
+		if v == nil {
+			continue
+		}
+		if versioning == nil {
+			versioning = &Versioning{}
+		}
+		if v.Latest != "" && (versioning.Latest == "" || CompareVersions(v.Latest, versioning.Latest) > 0) {
+			versioning.Latest = v.Latest
+		}
+		if v.Release != "" && (versioning.Release == "" || CompareVersions(v.Release, versioning.Release) > 0) {
+			versioning.Release = v.Release
+		}
+		if v.LastUpdated > versioning.LastUpdated {
+			versioning.LastUpdated = v.LastUpdated
+		}
+		if v.Snapshot != nil && newerSnapshot(v.Snapshot, versioning.Snapshot) {
+			versioning.Snapshot = v.Snapshot
+		}
+		for _, version := range v.Versions {
+			if !versionSet[version] {
+				versionSet[version] = true
+				versioning.Versions = append(versioning.Versions, version)
+			}
+		}
+		for _, sv := range v.SnapshotVersions {
+			key := sv.Classifier + ":" + sv.Extension
+			if current, ok := snapshotVersions[key]; !ok || sv.Updated > current.Updated ||
+				(sv.Updated == current.Updated && CompareVersions(sv.Value, current.Value) > 0) {
+				snapshotVersions[key] = sv
+			}
+		}
+	}
+
+	if versioning != nil {
+		sort.Slice(versioning.Versions, func(i, j int) bool {
+			return CompareVersions(versioning.Versions[i], versioning.Versions[j]) < 0
+		})
+		// 成员的 latest 可能缺失，以合并后最大的版本为准
+		if n := len(versioning.Versions); n > 0 && CompareVersions(versioning.Versions[n-1], versioning.Latest) > 0 {
+			versioning.Latest = versioning.Versions[n-1]
+		}
+
+		for _, sv := range snapshotVersions {
+			versioning.SnapshotVersions = append(versioning.SnapshotVersions, sv)
+		}
+		sort.Slice(versioning.SnapshotVersions, func(i, j int) bool {
+			a, b := versioning.SnapshotVersions[i], versioning.SnapshotVersions[j]
+			if a.Extension != b.Extension {
+				return a.Extension < b.Extension
+			}
+			return a.Classifier < b.Classifier
+		})
+	}
+	merged.Versioning = versioning
+	return merged
+}
+
+// newerSnapshot 判断快照构建 a 是否比 b 更新，b 为 nil 时返回 true
+func newerSnapshot(a, b *Snapshot) bool {
+	if b == nil {
+		return true
+	}
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp > b.Timestamp
+	}
+	return a.BuildNumber > b.BuildNumber
+}
@@ -0,0 +1,173 @@
+// pkg/metadata/metadata.go
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileName 仓库元数据文件名
+const FileName = "maven-metadata.xml"
+
+// 元数据中时间的格式
+const (
+	lastUpdatedFormat = "20060102150405"
+	timestampFormat   = "20060102.150405"
+)
+
+// ChecksumTypes 元数据文件需要生成的校验和类型
+var ChecksumTypes = []string{"md5", "sha1", "sha256", "sha512"}
+
+// Metadata maven-metadata.xml 的内容，artifact 级别包含版本列表，快照版本级别包含各文件的时间戳版本，
+// group 级别包含插件前缀
+type Metadata struct {
+	XMLName      xml.Name    `xml:"metadata"`
+	ModelVersion string      `xml:"modelVersion,attr,omitempty"`
+	GroupId      string      `xml:"groupId,omitempty"`
+	ArtifactId   string      `xml:"artifactId,omitempty"`
+	Version      string      `xml:"version,omitempty"`
+	Versioning   *Versioning `xml:"versioning,omitempty"`
+	Plugins      []Plugin    `xml:"plugins>plugin,omitempty"`
+}
+
+// Versioning 版本信息，字段顺序与 Maven 生成的一致
+type Versioning struct {
+	Latest           string            `xml:"latest,omitempty"`
+	Release          string            `xml:"release,omitempty"`
+	Snapshot         *Snapshot         `xml:"snapshot,omitempty"`
+	Versions         []string          `xml:"versions>version,omitempty"`
+	LastUpdated      string            `xml:"lastUpdated,omitempty"`
+	SnapshotVersions []SnapshotVersion `xml:"snapshotVersions>snapshotVersion,omitempty"`
+}
+
+// Snapshot 快照版本最新一次部署的时间戳和构建号
+type Snapshot struct {
+	Timestamp   string `xml:"timestamp,omitempty"`
+	BuildNumber int    `xml:"buildNumber,omitempty"`
+	LocalCopy   bool   `xml:"localCopy,omitempty"`
+}
+
+// SnapshotVersion 快照版本中某个 classifier 和扩展名对应的文件版本
+type SnapshotVersion struct {
+	Classifier string `xml:"classifier,omitempty"`
+	Extension  string `xml:"extension"`
+	Value      string `xml:"value"`
+	Updated    string `xml:"updated"`
+}
+
+// Plugin group 级别元数据中的插件
+type Plugin struct {
+	Name       string `xml:"name,omitempty"`
+	Prefix     string `xml:"prefix"`
+	ArtifactId string `xml:"artifactId"`
+}
+
+// Parse 解析 maven-metadata.xml
+func Parse(data []byte) (*Metadata, error) {
+	m := &Metadata{}
+	if err := xml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", FileName, err)
+	}
+	return m, nil
+}
+
+// Marshal 生成 maven-metadata.xml 的内容
+func (m *Metadata) Marshal() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(m.wire()); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// wireMetadata 序列化使用的结构，encoding/xml 对 a>b 形式的字段即使为空也会输出外层元素，
+// 因此列表使用指针包装，为空时省略
+type wireMetadata struct {
+	XMLName      xml.Name        `xml:"metadata"`
+	ModelVersion string          `xml:"modelVersion,attr,omitempty"`
+	GroupId      string          `xml:"groupId,omitempty"`
+	ArtifactId   string          `xml:"artifactId,omitempty"`
+	Version      string          `xml:"version,omitempty"`
+	Versioning   *wireVersioning `xml:"versioning,omitempty"`
+	Plugins      *wirePlugins    `xml:"plugins,omitempty"`
+}
+
+type wireVersioning struct {
+	Latest           string                `xml:"latest,omitempty"`
+	Release          string                `xml:"release,omitempty"`
+	Snapshot         *Snapshot             `xml:"snapshot,omitempty"`
+	Versions         *wireVersions         `xml:"versions,omitempty"`
+	LastUpdated      string                `xml:"lastUpdated,omitempty"`
+	SnapshotVersions *wireSnapshotVersions `xml:"snapshotVersions,omitempty"`
+}
+
+type wireVersions struct {
+	Version []string `xml:"version"`
+}
+
+type wireSnapshotVersions struct {
+	SnapshotVersion []SnapshotVersion `xml:"snapshotVersion"`
+}
+
+type wirePlugins struct {
+	Plugin []Plugin `xml:"plugin"`
+}
+
+func (m *Metadata) wire() *wireMetadata {
+	w := &wireMetadata{
+		ModelVersion: m.ModelVersion,
+		GroupId:      m.GroupId,
+		ArtifactId:   m.ArtifactId,
+		Version:      m.Version,
+	}
+	if len(m.Plugins) > 0 {
+		w.Plugins = &wirePlugins{Plugin: m.Plugins}
+	}
+	if v := m.Versioning; v != nil {
+		w.Versioning = &wireVersioning{
+			Latest:      v.Latest,
+			Release:     v.Release,
+			Snapshot:    v.Snapshot,
+			LastUpdated: v.LastUpdated,
+		}
+		if len(v.Versions) > 0 {
+			w.Versioning.Versions = &wireVersions{Version: v.Versions}
+		}
+		if len(v.SnapshotVersions) > 0 {
+			w.Versioning.SnapshotVersions = &wireSnapshotVersions{SnapshotVersion: v.SnapshotVersions}
+		}
+	}
+	return w
+}
+
+// IsMetadataPath 判断路径是否为元数据文件或其校验和文件
+func IsMetadataPath(p string) bool {
+	name := path.Base(p)
+	if name == FileName {
+		return true
+	}
+	for _, hashType := range ChecksumTypes {
+		if name == FileName+"."+hashType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSnapshot 判断是否为快照版本
+func IsSnapshot(version string) bool {
+	return strings.HasSuffix(version, "SNAPSHOT")
+}
+
+// formatLastUpdated 返回 lastUpdated 格式的 UTC 时间
+func formatLastUpdated(t time.Time) string {
+	return t.UTC().Format(lastUpdatedFormat)
+}
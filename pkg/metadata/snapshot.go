@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"maven-proxy/pkg/storage"
+)
+
+// ResolveSnapshot 将非唯一快照文件路径（如 /g/a/1.0-SNAPSHOT/a-1.0-SNAPSHOT-sources.jar）
+// 解析为最新的时间戳版本文件路径，校验和文件解析为对应构件的校验和
+//
+// 优先使用版本级别元数据中的 snapshotVersions，元数据不存在或没有对应条目时扫描目录。
+// 路径不是非唯一快照文件或没有时间戳版本时返回 false
+func ResolveSnapshot(s storage.Storage, p string) (string, bool) {
+	checksum := ""
+	for _, hashType := range ChecksumTypes {
+		if strings.HasSuffix(p, "."+hashType) {
+			checksum = "." + hashType
+			p = strings.TrimSuffix(p, checksum)
+			break
+		}
+	}
+
+	versionDir := path.Dir(p)
+	_, artifactId, version, ok := splitVersionDir(versionDir)
+	if !ok || !IsSnapshot(version) {
+		return "", false
+	}
+	requested, ok := ParseSnapshotFile(artifactId, version, path.Base(p), time.Time{})
+	if !ok || requested.Timestamp != "" {
+		return "", false
+	}
+
+	fileName := func(value string) string {
+		name := artifactId + "-" + value
+		if requested.Classifier != "" {
+			name += "-" + requested.Classifier
+		}
+		return path.Join(versionDir, name+"."+requested.Extension)
+	}
+
+	// 元数据可能落后于目录内容，指向的文件不存在时扫描目录
+	resolved := ""
+	if value := snapshotValueFromMetadata(s, versionDir, requested); value != "" && s.Exists(fileName(value)) {
+		resolved = fileName(value)
+	} else if value := snapshotValueFromListing(s, versionDir, requested); value != "" {
+		resolved = fileName(value)
+	}
+	if resolved == "" {
+		return "", false
+	}
+	return resolved + checksum, true
+}
+
+// snapshotValueFromMetadata 从版本级别元数据查找对应 classifier 和扩展名的文件版本
+func snapshotValueFromMetadata(s storage.Storage, versionDir string, requested *SnapshotFile) string {
+	data, err := storage.ReadBytes(s, path.Join(versionDir, FileName))
+	if err != nil {
+		return ""
+	}
+	m, err := Parse(data)
+	if err != nil || m.Versioning == nil {
+		return ""
+	}
+
+	for _, sv := range m.Versioning.SnapshotVersions {
+		if sv.Classifier == requested.Classifier && sv.Extension == requested.Extension && sv.Value != requested.Value {
+			return sv.Value
+		}
+	}
+	return ""
+}
+
+// snapshotValueFromListing 扫描目录查找对应 classifier 和扩展名最新的时间戳版本
+func snapshotValueFromListing(s storage.Storage, versionDir string, requested *SnapshotFile) string {
+	files, err := ListSnapshotFiles(s, versionDir)
+	if err != nil {
+		return ""
+	}
+
+	var newest *SnapshotFile
+	for _, file := range files {
+		if file.Timestamp == "" || file.Classifier != requested.Classifier || file.Extension != requested.Extension {
+			continue
+		}
+		if newest == nil || file.newerThan(newest) {
+			newest = file
+		}
+	}
+	if newest == nil {
+		return ""
+	}
+	return newest.Value
+}
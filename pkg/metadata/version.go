@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"strings"
+	"unicode"
+)
+
+// qualifierOrder 已知限定符的顺序，未知限定符排在所有已知限定符之后并按字母顺序比较
+var qualifierOrder = map[string]int{
+	"alpha":     1,
+	"a":         1,
+	"beta":      2,
+	"b":         2,
+	"milestone": 3,
+	"m":         3,
+	"rc":        4,
+	"cr":        4,
+	"snapshot":  5,
+	"":          6,
+	"ga":        6,
+	"final":     6,
+	"release":   6,
+	"sp":        7,
+}
+
+// versionItem 版本号中的一段，数字段按数值比较，其他按限定符比较
+type versionItem struct {
+	number    string // 去掉前导零的数字
+	qualifier string
+	isNumber  bool
+}
+
+// CompareVersions 按 Maven 的规则比较两个版本号，返回 -1、0 或 1
+//
+// 这是 Maven ComparableVersion 的简化实现：版本号按 .、- 以及数字与字母的交界切分，
+// 数字段按数值比较，限定符按 alpha < beta < milestone < rc < snapshot < 正式版 < sp 排序，
+// 缺少的段视为 0 或正式版，因此 1.0 与 1 相等，1.0-rc1 小于 1.0
+func CompareVersions(a, b string) int {
+	left, right := parseVersion(a), parseVersion(b)
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var x, y *versionItem
+		if i < len(left) {
+			x = &left[i]
+		}
+		if i < len(right) {
+			y = &right[i]
+		}
+		if c := compareItems(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func parseVersion(version string) []versionItem {
+	items := []versionItem{}
+	current := strings.Builder{}
+	digits := false
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		text := current.String()
+		current.Reset()
+		if digits {
+			number := strings.TrimLeft(text, "0")
+			if number == "" {
+				number = "0"
+			}
+			items = append(items, versionItem{number: number, isNumber: true})
+		} else {
+			items = append(items, versionItem{qualifier: strings.ToLower(text)})
+		}
+	}
+
+	for _, r := range version {
+		if r == '.' || r == '-' || r == '_' {
+			flush()
+			continue
+		}
+		isDigit := unicode.IsDigit(r)
+		if current.Len() > 0 && isDigit != digits {
+			flush()
+		}
+		digits = isDigit
+		current.WriteRune(r)
+	}
+	flush()
+
+	// 去掉末尾等同于缺省值的段，使 1.0.0 与 1 相等
+	for len(items) > 0 {
+		last := items[len(items)-1]
+		if (last.isNumber && last.number == "0") || (!last.isNumber && qualifierOrder[last.qualifier] == qualifierOrder[""]) {
+			items = items[:len(items)-1]
+			continue
+		}
+		break
+	}
+	return items
+}
+
+// compareItems 比较两段，nil 表示该段缺失
+func compareItems(x, y *versionItem) int {
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -compareItems(y, nil)
+	case y == nil:
+		if x.isNumber {
+			if x.number == "0" {
+				return 0
+			}
+			return 1
+		}
+		return compareQualifiers(x.qualifier, "")
+	case x.isNumber && y.isNumber:
+		if len(x.number) != len(y.number) {
+			return sign(len(x.number) - len(y.number))
+		}
+		return strings.Compare(x.number, y.number)
+	case x.isNumber:
+		// 数字段大于限定符，如 1.0.1 大于 1.0-rc
+		return 1
+	case y.isNumber:
+		return -1
+	default:
+		return compareQualifiers(x.qualifier, y.qualifier)
+	}
+}
+
+func compareQualifiers(x, y string) int {
+	ox, knownX := qualifierRank(x)
+	oy, knownY := qualifierRank(y)
+	if knownX && knownY {
+		return sign(ox - oy)
+	}
+	if knownX != knownY {
+		if knownX {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(x, y)
+}
+
+func qualifierRank(q string) (int, bool) {
+	rank, ok := qualifierOrder[q]
+	return rank, ok
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -3,10 +3,12 @@ package repository
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"sort"
 	"strings"
 
+	"maven-proxy/pkg/metadata"
 	"maven-proxy/pkg/storage"
 )
 
@@ -35,36 +37,52 @@ func (r *GroupRepository) Type() string {
 }
 
 func (r *GroupRepository) CanRead() bool {
-	return r.mode&4 == 4
+	return r.mode&ModeRead == ModeRead
 }
 
 func (r *GroupRepository) CanWrite() bool {
-	return r.mode&2 == 2
+	return r.mode&ModeWrite == ModeWrite
 }
 
-func (r *GroupRepository) Get(path string) ([]byte, int, http.Header, error) {
-	// 按优先级遍历成员仓库
+func (r *GroupRepository) CanDelete() bool {
+	return false
+}
+
+func (r *GroupRepository) Get(path string) (*storage.Content, int, error) {
+	if metadata.IsMetadataPath(path) {
+		return r.getMetadata(path)
+	}
+
+	// 按优先级遍历成员仓库，代理成员的上游不可用时不能确定文件不存在，返回该成员的错误
+	var unavailable error
 	for _, member := range r.members {
 		if !member.CanRead() {
 			continue
 		}
 
-		if data, status, headers, err := member.Get(path); err == nil {
-			return data, status, headers, nil
+		content, status, err := member.Get(path)
+		if err == nil {
+			return content, status, nil
+		}
+		if status == http.StatusBadGateway && unavailable == nil {
+			unavailable = err
 		}
 	}
 
-	return nil, http.StatusNotFound, nil, errors.New("artifact not found in any member repository")
+	if unavailable != nil {
+		return nil, http.StatusBadGateway, unavailable
+	}
+	return nil, http.StatusNotFound, errors.New("artifact not found in any member repository")
 }
 
-func (r *GroupRepository) Put(path string, data []byte) error {
+func (r *GroupRepository) Put(path string, reader io.Reader, size int64) error {
 	// 根据路由规则选择目标仓库
 	targetRepo := r.routeToTarget(path)
 	if targetRepo == nil {
 		return errors.New("no target repository for path")
 	}
 
-	return targetRepo.Put(path, data)
+	return targetRepo.Put(path, reader, size)
 }
 
 func (r *GroupRepository) List(path string) ([]storage.FileInfo, error) {
@@ -99,6 +117,79 @@ func (r *GroupRepository) List(path string) ([]storage.FileInfo, error) {
 	return result, nil
 }
 
+func (r *GroupRepository) Stat(path string) (*storage.FileInfo, error) {
+	// 元数据由各成员合并生成，没有存储的文件可以查询
+	if metadata.IsMetadataPath(path) {
+		return nil, storage.ErrNotFound
+	}
+
+	// 按优先级返回第一个存在该文件的成员仓库的结果
+	for _, member := range r.members {
+		if !member.CanRead() {
+			continue
+		}
+
+		if info, err := member.Stat(path); err == nil {
+			return info, nil
+		}
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+// getMetadata 合并所有可读成员的元数据，校验和根据合并后的内容计算
+func (r *GroupRepository) getMetadata(p string) (*storage.Content, int, error) {
+	target, hashType := splitChecksum(p)
+
+	items := []*metadata.Metadata{}
+	for _, member := range r.members {
+		if !member.CanRead() {
+			continue
+		}
+		content, _, err := member.Get(target)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			log.Warnf("read %s from %s failed: %v", target, member.ID(), err)
+			continue
+		}
+		m, err := metadata.Parse(data)
+		if err != nil {
+			log.Warnf("ignore %s from %s: %v", target, member.ID(), err)
+			continue
+		}
+		items = append(items, m)
+	}
+	if len(items) == 0 {
+		return nil, http.StatusNotFound, errors.New("metadata not found in any member repository")
+	}
+
+	data, err := metadata.Merge(items).Marshal()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return metadataContent(p, data, hashType)
+}
+
+// Touch 记录到按优先级第一个存在该文件的成员仓库，与 Get 的选择一致，合并生成的元数据不记录
+func (r *GroupRepository) Touch(path string) {
+	if metadata.IsMetadataPath(path) {
+		return
+	}
+	for _, member := range r.members {
+		if !member.CanRead() {
+			continue
+		}
+		if _, err := member.Stat(path); err == nil {
+			member.Touch(path)
+			return
+		}
+	}
+}
+
 func (r *GroupRepository) routeToTarget(path string) Repository {
 	isSnapshot := strings.Contains(strings.ToLower(path), "-snapshot")
 
@@ -117,3 +208,7 @@ func (r *GroupRepository) routeToTarget(path string) Repository {
 
 	return nil
 }
+
+func (r *GroupRepository) Delete(path string) error {
+	return ErrNotSupported
+}
@@ -2,15 +2,19 @@
 package repository
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
+	"maven-proxy/pkg/metadata"
 	"maven-proxy/pkg/storage"
 )
 
 type HostedRepository struct {
-	id      string
-	mode    int
-	storage storage.Storage
+	id            string
+	mode          int
+	storage       storage.Storage
+	metadataLocks *dirLocks // 为 nil 时不维护元数据
 }
 
 func NewHostedRepository(id string, mode int, storage storage.Storage) *HostedRepository {
@@ -30,21 +34,87 @@ func (r *HostedRepository) Type() string {
 }
 
 func (r *HostedRepository) CanRead() bool {
-	return r.mode&4 == 4
+	return r.mode&ModeRead == ModeRead
 }
 
 func (r *HostedRepository) CanWrite() bool {
-	return r.mode&2 == 2
+	return r.mode&ModeWrite == ModeWrite
 }
 
-func (r *HostedRepository) Get(path string) ([]byte, int, http.Header, error) {
-	return r.storage.Read(path)
+func (r *HostedRepository) CanDelete() bool {
+	return r.mode&ModeDelete == ModeDelete
 }
 
-func (r *HostedRepository) Put(path string, data []byte) error {
-	return r.storage.Write(path, data)
+func (r *HostedRepository) Get(path string) (*storage.Content, int, error) {
+	content, err := r.storage.Read(path)
+	if resolved, ok := r.resolveSnapshot(path, err); ok {
+		content, err = r.storage.Read(resolved)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return content, http.StatusOK, nil
+}
+
+func (r *HostedRepository) Put(path string, reader io.Reader, size int64) error {
+	if r.metadataLocks != nil && metadata.IsMetadataPath(path) {
+		return r.putMetadata(path, reader, size)
+	}
+	if err := r.storage.Write(path, reader, size); err != nil {
+		return err
+	}
+	// 构件已经保存，元数据更新失败（如超出配额）时不能让客户端误以为部署失败，下次部署或删除时重新生成
+	if err := r.RefreshMetadata(path); err != nil {
+		log.Warnf("update metadata after deploying %s failed: %v", path, err)
+	}
+	return nil
 }
 
 func (r *HostedRepository) List(path string) ([]storage.FileInfo, error) {
 	return r.storage.List(path)
 }
+
+func (r *HostedRepository) Stat(path string) (*storage.FileInfo, error) {
+	info, err := r.storage.Stat(path)
+	if resolved, ok := r.resolveSnapshot(path, err); ok {
+		info, err = r.storage.Stat(resolved)
+	}
+	return info, err
+}
+
+// Touch 记录客户端访问，非唯一快照记录解析后的时间戳版本
+func (r *HostedRepository) Touch(path string) {
+	_, err := r.storage.Stat(path)
+	if resolved, ok := r.resolveSnapshot(path, err); ok {
+		path = resolved
+	}
+	storage.Touch(r.storage, path)
+}
+
+// resolveSnapshot 非唯一快照文件不存在时解析为最新的时间戳版本
+func (r *HostedRepository) resolveSnapshot(path string, err error) (string, bool) {
+	if !errors.Is(err, storage.ErrNotFound) {
+		return "", false
+	}
+	return metadata.ResolveSnapshot(r.storage, path)
+}
+
+func (r *HostedRepository) Delete(path string) error {
+	if err := r.storage.Delete(path); err != nil {
+		return err
+	}
+
+	// 删除单个文件时同时删除对应的校验和文件
+	for _, ext := range checksumExtensions {
+		if err := r.storage.Delete(path + ext); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	if err := r.RefreshMetadata(path); err != nil {
+		log.Warnf("update metadata after deleting %s failed: %v", path, err)
+	}
+	return nil
+}
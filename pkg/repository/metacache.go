@@ -0,0 +1,139 @@
+// pkg/repository/metacache.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"maven-proxy/pkg/storage"
+)
+
+// metadataValidators 上游返回的验证信息，用于过期后的条件请求
+type metadataValidators struct {
+	etag         string
+	lastModified string
+}
+
+// EnableMetadataCache 缓存上游的 maven-metadata.xml，ttl 内直接使用缓存，过期后向上游发送条件请求重新验证，
+// 所有镜像都不可用时继续使用过期的缓存
+//
+// 缓存文件的修改时间即最后一次获取或验证的时间。上游的 ETag 只保存在内存中，
+// 重启后以缓存文件的修改时间作为 If-Modified-Since。校验和根据缓存的内容计算，与元数据始终一致
+func (r *ProxyRepository) EnableMetadataCache(ttl time.Duration) {
+	r.metadataTTL = ttl
+	r.metadataLocks = newDirLocks()
+	r.validators = make(map[string]metadataValidators)
+}
+
+// getMetadata 返回缓存或从上游获取的元数据及其校验和
+func (r *ProxyRepository) getMetadata(p string) (*storage.Content, int, error) {
+	target, hashType := splitChecksum(p)
+	data, err := r.fetchMetadata(target)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, http.StatusNotFound, errNotFoundInMirrors
+		}
+		return nil, http.StatusBadGateway, err
+	}
+	return metadataContent(p, data, hashType)
+}
+
+// fetchMetadata 读取元数据，缓存过期时向上游重新验证
+func (r *ProxyRepository) fetchMetadata(p string) ([]byte, error) {
+	unlock := r.metadataLocks.lock(p)
+	defer unlock()
+
+	info, err := r.storage.Stat(p)
+	cached := err == nil && !info.IsDir
+	if cached && !r.metadataExpired(info) {
+		if data, err := storage.ReadBytes(r.storage, p); err == nil {
+			return data, nil
+		}
+		cached = false
+	}
+
+	header := http.Header{}
+	if cached {
+		r.validatorsMu.Lock()
+		v := r.validators[p]
+		r.validatorsMu.Unlock()
+		if v.etag != "" {
+			header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			header.Set("If-Modified-Since", v.lastModified)
+		} else {
+			header.Set("If-Modified-Since", info.ModTime.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	unavailable := false
+	for _, mirror := range r.mirrors {
+		url := mirror + p
+		body, status, headers, err := r.client.GetWithHeader(url, header)
+		log.Debugf("fetch %s: status=%d err=%v", url, status, err)
+		if err != nil || status >= http.StatusInternalServerError {
+			unavailable = true
+			continue
+		}
+
+		switch {
+		case status == http.StatusNotModified && cached:
+			data, err := storage.ReadBytes(r.storage, p)
+			if err != nil {
+				return nil, err
+			}
+			// 重新写入以更新获取时间
+			if err := storage.WriteBytes(r.storage, p, data); err != nil {
+				log.Warnf("refresh cached %s failed: %v", p, err)
+			}
+			return data, nil
+
+		case status == http.StatusOK:
+			data, err := io.ReadAll(body)
+			body.Close()
+			if err != nil {
+				unavailable = true
+				continue
+			}
+			if err := storage.WriteBytes(r.storage, p, data); err != nil {
+				log.Warnf("cache %s failed: %v", p, err)
+			}
+			r.validatorsMu.Lock()
+			r.validators[p] = metadataValidators{
+				etag:         headers.Get("ETag"),
+				lastModified: headers.Get("Last-Modified"),
+			}
+			r.validatorsMu.Unlock()
+			return data, nil
+		}
+	}
+
+	if !cached {
+		if unavailable {
+			return nil, fmt.Errorf("fetch %s failed: all mirrors unavailable", p)
+		}
+		return nil, storage.ErrNotFound
+	}
+	if unavailable {
+		log.Warnf("revalidate %s failed, serving stale cached metadata", p)
+		return storage.ReadBytes(r.storage, p)
+	}
+
+	// 所有镜像都明确返回不存在，删除缓存
+	if err := r.storage.Delete(p); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Warnf("remove cached %s failed: %v", p, err)
+	}
+	r.validatorsMu.Lock()
+	delete(r.validators, p)
+	r.validatorsMu.Unlock()
+	return nil, storage.ErrNotFound
+}
+
+// metadataExpired 判断缓存的元数据是否超过 TTL
+func (r *ProxyRepository) metadataExpired(info *storage.FileInfo) bool {
+	return time.Since(info.ModTime) >= r.metadataTTL
+}
@@ -0,0 +1,227 @@
+// pkg/repository/metadata.go
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"maven-proxy/internal/util"
+	"maven-proxy/pkg/metadata"
+	"maven-proxy/pkg/storage"
+)
+
+// EnableMetadata 由仓库维护 maven-metadata.xml，每次部署和删除后根据存储中的文件重新生成
+// artifact 级别和快照版本级别的元数据及其校验和，客户端上传的这两级元数据会被忽略
+func (r *HostedRepository) EnableMetadata() {
+	r.metadataLocks = newDirLocks()
+}
+
+// RefreshMetadata 在 path 被写入或删除后更新受影响的元数据
+func (r *HostedRepository) RefreshMetadata(p string) error {
+	if r.metadataLocks == nil {
+		return nil
+	}
+
+	if metadata.IsMetadataPath(p) {
+		if dir := path.Dir(p); r.isManagedDir(dir) {
+			return r.updateMetadata(dir)
+		}
+		return nil
+	}
+	if isChecksumPath(p) {
+		return nil
+	}
+
+	versionDir, artifactDir, ok := metadata.ArtifactPaths(p)
+	if !ok {
+		// 删除整个版本目录时更新构件目录的元数据
+		if parent := path.Dir(p); r.hasOwnMetadata(parent) {
+			return r.updateMetadata(parent)
+		}
+		return nil
+	}
+	if metadata.IsSnapshot(path.Base(versionDir)) {
+		if err := r.updateMetadata(versionDir); err != nil {
+			return err
+		}
+	}
+	return r.updateMetadata(artifactDir)
+}
+
+// putMetadata 处理客户端上传的元数据，由仓库维护的目录重新生成，其他（如 group 级别的插件元数据）按原样保存
+func (r *HostedRepository) putMetadata(p string, reader io.Reader, size int64) error {
+	dir := path.Dir(p)
+	if !r.isManagedDir(dir) {
+		return r.storage.Write(p, reader, size)
+	}
+
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return err
+	}
+	if path.Base(p) != metadata.FileName {
+		return nil
+	}
+	return r.updateMetadata(dir)
+}
+
+// updateMetadata 重新生成目录的元数据，目录中已没有构件时删除元数据
+//
+// 多个实例共享存储时在跨进程锁内列出目录并写入，后获得锁的实例总能看到先写入的构件
+func (r *HostedRepository) updateMetadata(dir string) error {
+	unlock := r.metadataLocks.lock(dir)
+	defer unlock()
+
+	unlockShared, err := storage.LockPath(r.storage, dir)
+	if err != nil {
+		return fmt.Errorf("lock metadata of %s failed: %w", dir, err)
+	}
+	defer unlockShared()
+
+	var m *metadata.Metadata
+	if metadata.IsSnapshot(path.Base(dir)) {
+		m, err = metadata.BuildSnapshot(r.storage, dir)
+	} else {
+		m, err = metadata.BuildArtifact(r.storage, dir)
+	}
+	if err != nil {
+		return fmt.Errorf("build metadata of %s failed: %w", dir, err)
+	}
+
+	target := path.Join(dir, metadata.FileName)
+	if m == nil {
+		if !r.hasOwnMetadata(dir) {
+			return nil
+		}
+		if err := r.storage.Delete(target); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		for _, hashType := range metadata.ChecksumTypes {
+			if err := r.storage.Delete(target + "." + hashType); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	sums, err := util.ComputeHashes(bytes.NewReader(data), metadata.ChecksumTypes...)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.WriteBytes(r.storage, target, data); err != nil {
+		return fmt.Errorf("write %s failed: %w", target, err)
+	}
+	for _, hashType := range metadata.ChecksumTypes {
+		if err := storage.WriteBytes(r.storage, target+"."+hashType, []byte(sums[hashType])); err != nil {
+			return fmt.Errorf("write %s.%s failed: %w", target, hashType, err)
+		}
+	}
+	return nil
+}
+
+// isManagedDir 判断目录的元数据是否由仓库维护
+func (r *HostedRepository) isManagedDir(dir string) bool {
+	if metadata.IsSnapshot(path.Base(dir)) {
+		files, err := metadata.ListSnapshotFiles(r.storage, dir)
+		return err == nil && len(files) > 0
+	}
+	return metadata.IsArtifactDir(r.storage, dir)
+}
+
+// hasOwnMetadata 判断目录中是否有属于该目录本身的 artifact 或快照版本级别元数据，
+// group 级别的插件元数据不属于仓库维护的范围
+func (r *HostedRepository) hasOwnMetadata(dir string) bool {
+	data, err := storage.ReadBytes(r.storage, path.Join(dir, metadata.FileName))
+	if err != nil {
+		return false
+	}
+	m, err := metadata.Parse(data)
+	if err != nil {
+		return false
+	}
+	if metadata.IsSnapshot(path.Base(dir)) {
+		return m.Version == path.Base(dir)
+	}
+	return len(m.Plugins) == 0 && m.ArtifactId == path.Base(dir)
+}
+
+// isChecksumPath 判断是否为校验和文件
+func isChecksumPath(p string) bool {
+	for _, ext := range checksumExtensions {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitChecksum 将元数据校验和路径拆分为元数据路径和校验和类型，不是校验和时类型为空
+func splitChecksum(p string) (string, string) {
+	for _, hashType := range metadata.ChecksumTypes {
+		if strings.HasSuffix(p, "."+hashType) {
+			return strings.TrimSuffix(p, "."+hashType), hashType
+		}
+	}
+	return p, ""
+}
+
+// metadataContent 返回元数据内容，hashType 不为空时返回根据内容计算的校验和
+func metadataContent(p string, data []byte, hashType string) (*storage.Content, int, error) {
+	if hashType == "" {
+		return storage.NewContent(p, data), http.StatusOK, nil
+	}
+	sums, err := util.ComputeHashes(bytes.NewReader(data), hashType)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return storage.NewContent(p, []byte(sums[hashType])), http.StatusOK, nil
+}
+
+// dirLocks 按目录或路径串行化元数据的生成和更新，锁在无人持有时回收
+type dirLocks struct {
+	mu    sync.Mutex
+	locks map[string]*dirLock
+}
+
+type dirLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newDirLocks() *dirLocks {
+	return &dirLocks{locks: make(map[string]*dirLock)}
+}
+
+// lock 获取目录或路径的锁，返回解锁函数
+func (l *dirLocks) lock(dir string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[dir]
+	if !ok {
+		lock = &dirLock{}
+		l.locks[dir] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, dir)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,134 @@
+// pkg/repository/notfound.go
+package repository
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"maven-proxy/pkg/metadata"
+)
+
+// NotFoundCache 记录所有镜像都返回 404 的路径，TTL 内再次请求时直接返回不存在，不访问上游
+// 按条目数限制大小，超出时淘汰最早记录的路径。网络错误和 5xx 不会被记录
+type NotFoundCache struct {
+	ttl             time.Duration
+	maxEntries      int
+	excludeMetadata bool
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// NotFoundCacheStats 404 缓存统计信息
+type NotFoundCacheStats struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Entries    int    `json:"entries"`
+	MaxEntries int    `json:"maxEntries"`
+	TTL        string `json:"ttl"`
+}
+
+type notFoundEntry struct {
+	path    string
+	expires time.Time
+}
+
+// NewNotFoundCache 创建 404 缓存，excludeMetadata 为 true 时不缓存元数据路径
+func NewNotFoundCache(ttl time.Duration, maxEntries int, excludeMetadata bool) *NotFoundCache {
+	return &NotFoundCache{
+		ttl:             ttl,
+		maxEntries:      maxEntries,
+		excludeMetadata: excludeMetadata,
+		entries:         make(map[string]*list.Element),
+		order:           list.New(),
+	}
+}
+
+// Contains 判断路径是否在 TTL 内被记录为不存在
+func (c *NotFoundCache) Contains(path string) bool {
+	if !c.applies(path) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[path]
+	if ok && time.Now().Before(elem.Value.(*notFoundEntry).expires) {
+		atomic.AddUint64(&c.hits, 1)
+		return true
+	}
+	if ok {
+		c.remove(elem)
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return false
+}
+
+// Add 记录路径不存在
+func (c *NotFoundCache) Add(path string) {
+	if !c.applies(path) || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.remove(elem)
+	}
+	c.entries[path] = c.order.PushBack(&notFoundEntry{path: path, expires: time.Now().Add(c.ttl)})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Front())
+	}
+}
+
+// Clear 删除以 prefix 开头的记录，prefix 为空时清空，返回删除的条目数
+func (c *NotFoundCache) Clear(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prefix == "" {
+		n := c.order.Len()
+		c.entries = make(map[string]*list.Element)
+		c.order.Init()
+		return n
+	}
+
+	n := 0
+	for path, elem := range c.entries {
+		if strings.HasPrefix(path, prefix) {
+			c.remove(elem)
+			n++
+		}
+	}
+	return n
+}
+
+// Stats 返回统计信息
+func (c *NotFoundCache) Stats() NotFoundCacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+	return NotFoundCacheStats{
+		Hits:       atomic.LoadUint64(&c.hits),
+		Misses:     atomic.LoadUint64(&c.misses),
+		Entries:    entries,
+		MaxEntries: c.maxEntries,
+		TTL:        c.ttl.String(),
+	}
+}
+
+// applies 判断路径是否使用 404 缓存
+func (c *NotFoundCache) applies(path string) bool {
+	return !c.excludeMetadata || !metadata.IsMetadataPath(path)
+}
+
+func (c *NotFoundCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*notFoundEntry).path)
+}
@@ -2,13 +2,19 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"maven-proxy/pkg/client"
+	"maven-proxy/pkg/metadata"
 	"maven-proxy/pkg/storage"
 )
 
@@ -29,6 +35,14 @@ type ProxyRepository struct {
 	mirrors []string
 	storage storage.Storage
 	client  client.HTTPClient
+
+	notFound *NotFoundCache
+
+	// 以下字段仅在启用元数据缓存后使用
+	metadataTTL   time.Duration
+	metadataLocks *dirLocks
+	validatorsMu  sync.Mutex
+	validators    map[string]metadataValidators
 }
 
 func NewProxyRepository(id string, mode int, cache bool, mirrors []string, storage storage.Storage) *ProxyRepository {
@@ -51,41 +65,90 @@ func (r *ProxyRepository) Type() string {
 }
 
 func (r *ProxyRepository) CanRead() bool {
-	return r.mode&4 == 4
+	return r.mode&ModeRead == ModeRead
 }
 
 func (r *ProxyRepository) CanWrite() bool {
 	return false
 }
 
-func (r *ProxyRepository) Get(path string) ([]byte, int, http.Header, error) {
+func (r *ProxyRepository) CanDelete() bool {
+	return false
+}
+
+// SetNotFoundCache 设置 404 缓存，所有镜像都返回 404 的路径在 TTL 内不再访问上游
+func (r *ProxyRepository) SetNotFoundCache(cache *NotFoundCache) {
+	r.notFound = cache
+}
+
+// NotFoundCache 返回 404 缓存，未启用时返回 nil
+func (r *ProxyRepository) NotFoundCache() *NotFoundCache {
+	return r.notFound
+}
+
+func (r *ProxyRepository) Get(path string) (*storage.Content, int, error) {
+	if r.notFound != nil && r.notFound.Contains(path) {
+		return nil, http.StatusNotFound, errNotFoundInMirrors
+	}
+
+	if r.metadataLocks != nil && metadata.IsMetadataPath(path) {
+		content, status, err := r.getMetadata(path)
+		if status == http.StatusNotFound && r.notFound != nil {
+			r.notFound.Add(path)
+		}
+		return content, status, err
+	}
+
 	// 先尝试从本地缓存读取
-	if data, status, headers, err := r.storage.Read(path); err == nil {
-		return data, status, headers, nil
+	if content, err := r.storage.Read(path); err == nil {
+		return content, http.StatusOK, nil
 	}
 
 	// 从远程镜像获取
+	unavailable := false
 	for _, mirror := range r.mirrors {
 		url := mirror + path
-		data, status, headers, err := r.client.Get(url)
-		log.Debugf("%d", status, headers, err)
-		if err != nil {
+		body, status, headers, err := r.client.Get(url)
+		log.Debugf("fetch %s: status=%d err=%v", url, status, err)
+		if err != nil || status >= http.StatusInternalServerError {
+			unavailable = true
+		}
+		if err != nil || status != http.StatusOK {
 			continue
 		}
 
-		if status == http.StatusOK {
-			// 如果启用缓存且不是 metadata 文件，保存到本地
-			if r.cache && !strings.Contains(strings.ToLower(path), "maven-metadata.xml") {
-				r.storage.Write(path, data)
-			}
-			return data, status, headers, nil
+		size, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+		if err != nil {
+			size = -1
+		}
+		contentType := headers.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		// 如果启用缓存且不是 metadata 文件，在返回给客户端的同时写入本地
+		if r.cache && !strings.Contains(strings.ToLower(path), "maven-metadata.xml") {
+			body = newCachingReader(r.storage, path, body, size)
 		}
+
+		return &storage.Content{
+			ReadCloser:  body,
+			Size:        size,
+			ContentType: contentType,
+		}, http.StatusOK, nil
 	}
 
-	return nil, http.StatusNotFound, nil, fmt.Errorf("artifact not found in any mirror")
+	// 有镜像不可用时无法确定文件不存在，返回 502 且不记录，只有上游明确返回不存在时才返回 404
+	if unavailable {
+		return nil, http.StatusBadGateway, errMirrorsUnavailable
+	}
+	if r.notFound != nil {
+		r.notFound.Add(path)
+	}
+	return nil, http.StatusNotFound, errNotFoundInMirrors
 }
 
-func (r *ProxyRepository) Put(path string, data []byte) error {
+func (r *ProxyRepository) Put(path string, reader io.Reader, size int64) error {
 	return fmt.Errorf("proxy repository does not support write operations")
 }
 
@@ -93,3 +156,85 @@ func (r *ProxyRepository) List(path string) ([]storage.FileInfo, error) {
 	// Proxy 仓库的目录列表来自本地缓存
 	return r.storage.List(path)
 }
+
+// errNotFoundInMirrors 所有镜像都没有请求的文件
+var errNotFoundInMirrors = errors.New("artifact not found in any mirror")
+
+// errMirrorsUnavailable 没有镜像返回文件，且至少一个镜像出错或返回 5xx
+var errMirrorsUnavailable = errors.New("artifact not found, some mirrors are unavailable")
+
+// errCacheAborted 客户端未读完上游内容，放弃本次缓存
+var errCacheAborted = errors.New("upstream read aborted before EOF")
+
+// cachingReader 将上游响应体转发给调用方的同时写入存储
+// 只有完整读到 EOF 的内容才会被缓存，写缓存失败不影响调用方读取
+type cachingReader struct {
+	body   io.ReadCloser
+	path   string
+	pw     *io.PipeWriter
+	done   chan error
+	eof    bool
+	failed bool
+}
+
+func newCachingReader(s storage.Storage, path string, body io.ReadCloser, size int64) *cachingReader {
+	pr, pw := io.Pipe()
+	r := &cachingReader{
+		body: body,
+		path: path,
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := s.Write(path, pr, size)
+		// 写入提前结束时让后续的 pipe 写入立即返回，避免阻塞读取方
+		pr.CloseWithError(err)
+		r.done <- err
+	}()
+
+	return r
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 && !r.failed {
+		if _, werr := r.pw.Write(p[:n]); werr != nil {
+			r.failed = true
+		}
+	}
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+func (r *cachingReader) Close() error {
+	if r.eof {
+		r.pw.Close()
+	} else {
+		r.pw.CloseWithError(errCacheAborted)
+	}
+
+	if err := <-r.done; err != nil && r.eof {
+		log.Warnf("cache %s failed: %v", r.path, err)
+	}
+	return r.body.Close()
+}
+
+// Stat 只查询本地缓存，不访问远程镜像，过期的元数据视为不存在，由 Get 重新验证
+func (r *ProxyRepository) Stat(path string) (*storage.FileInfo, error) {
+	info, err := r.storage.Stat(path)
+	if err == nil && r.metadataLocks != nil && metadata.IsMetadataPath(path) && r.metadataExpired(info) {
+		return nil, storage.ErrNotFound
+	}
+	return info, err
+}
+
+func (r *ProxyRepository) Touch(path string) {
+	storage.Touch(r.storage, path)
+}
+
+func (r *ProxyRepository) Delete(path string) error {
+	return ErrNotSupported
+}
@@ -2,11 +2,25 @@
 package repository
 
 import (
-	"net/http"
+	"errors"
+	"io"
 
 	"maven-proxy/pkg/storage"
 )
 
+// 仓库权限位，对应配置中的 mode
+const (
+	ModeRead   = 4 // 读取
+	ModeWrite  = 2 // 上传
+	ModeDelete = 1 // 删除
+)
+
+// ErrNotSupported 仓库类型不支持该操作
+var ErrNotSupported = errors.New("operation not supported by repository type")
+
+// checksumExtensions 删除文件时一并删除的校验和文件扩展名
+var checksumExtensions = []string{".md5", ".sha1", ".sha256", ".sha512"}
+
 // Repository 仓库接口定义
 type Repository interface {
 	// ID 返回仓库唯一标识
@@ -21,12 +35,25 @@ type Repository interface {
 	// CanWrite 检查是否有写权限
 	CanWrite() bool
 
-	// Get 获取文件，返回数据、状态码、响应头和错误
-	Get(path string) ([]byte, int, http.Header, error)
+	// CanDelete 检查是否有删除权限
+	CanDelete() bool
 
-	// Put 上传文件
-	Put(path string, data []byte) error
+	// Get 获取文件，返回可流式读取的内容、状态码和错误，调用方负责关闭内容
+	Get(path string) (*storage.Content, int, error)
+
+	// Put 从 reader 流式上传文件，size 为 -1 表示长度未知
+	Put(path string, r io.Reader, size int64) error
 
 	// List 列出目录内容
 	List(path string) ([]storage.FileInfo, error)
+
+	// Stat 返回已存储文件的元信息，不读取文件内容
+	Stat(path string) (*storage.FileInfo, error)
+
+	// Delete 删除文件（及其校验和文件）或整个目录
+	Delete(path string) error
+
+	// Touch 记录客户端对文件的访问，供分层存储和缓存淘汰使用。
+	// 只在响应客户端请求后调用，导出等后台任务通过 Get 读取时不调用
+	Touch(path string)
 }
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+// attributesSuffix 旁路属性文件后缀，文件 foo.jar 的属性保存在 .foo.jar.attributes
+const attributesSuffix = ".attributes"
+
+// attributes 写入时记录的文件属性
+// Size 和 ModTime 用于校验属性是否仍然对应当前文件，文件被绕过存储层修改后摘要失效
+type attributes struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	ContentType string    `json:"contentType"`
+	SHA1        string    `json:"sha1"`
+	SHA256      string    `json:"sha256"`
+}
+
+// attributesName 返回文件对应的属性文件名
+func attributesName(name string) string {
+	return "." + name + attributesSuffix
+}
+
+// isHiddenFile 判断是否为不对外展示的临时文件、锁文件或属性文件
+func isHiddenFile(name string) bool {
+	return isTempFile(name) || isLockFile(name) || (strings.HasPrefix(name, ".") && strings.HasSuffix(name, attributesSuffix))
+}
+
+// parseAttributes 解析属性文件，与文件当前状态不符时不返回摘要
+func parseAttributes(data []byte, size int64, modTime time.Time) (*attributes, error) {
+	attrs := &attributes{}
+	if err := json.Unmarshal(data, attrs); err != nil {
+		return nil, fmt.Errorf("parse attributes failed: %w", err)
+	}
+	if attrs.Size != size || (!modTime.IsZero() && !attrs.ModTime.Equal(modTime)) {
+		return nil, fmt.Errorf("attributes are stale")
+	}
+	return attrs, nil
+}
+
+// digestReader 在读取的同时计算 SHA-1 和 SHA-256
+type digestReader struct {
+	r      io.Reader
+	sha1   hash.Hash
+	sha256 hash.Hash
+	n      int64
+}
+
+func newDigestReader(r io.Reader) *digestReader {
+	return &digestReader{
+		r:      r,
+		sha1:   sha1.New(),
+		sha256: sha256.New(),
+	}
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sha1.Write(p[:n])
+		r.sha256.Write(p[:n])
+		r.n += int64(n)
+	}
+	return n, err
+}
+
+// attributes 生成已读取内容的属性
+func (r *digestReader) attributes(path string, modTime time.Time) *attributes {
+	return &attributes{
+		Size:        r.n,
+		ModTime:     modTime,
+		ContentType: getContentType(path),
+		SHA1:        fmt.Sprintf("%x", r.sha1.Sum(nil)),
+		SHA256:      fmt.Sprintf("%x", r.sha256.Sum(nil)),
+	}
+}
@@ -0,0 +1,408 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// BlobStorage 内容寻址存储，相同内容的文件只按 SHA-256 保存一份
+//
+// 目录结构：
+//
+//	blobs/sha256/<前两位>/<digest>  文件内容
+//	refs/<path>                     路径到 digest 的映射
+//
+// 引用计数在启动时由 refs 重建，不单独持久化，崩溃后不会出现计数漂移
+type BlobStorage struct {
+	basePath string
+	blobDir  string
+	refDir   string
+	locks    *pathLocker
+
+	mu       sync.RWMutex
+	refCount map[string]int
+}
+
+// blobRef 路径引用文件内容
+type blobRef struct {
+	Digest string `json:"digest"` // SHA-256
+	SHA1   string `json:"sha1"`
+	Size   int64  `json:"size"`
+}
+
+// NewBlobStorage 创建内容寻址存储，扫描已有引用并清理无引用的内容
+func NewBlobStorage(basePath string) (*BlobStorage, error) {
+	s := &BlobStorage{
+		basePath: basePath,
+		blobDir:  filepath.Join(basePath, "blobs", "sha256"),
+		refDir:   filepath.Join(basePath, "refs"),
+		locks:    newPathLocker(),
+		refCount: make(map[string]int),
+	}
+
+	for _, dir := range []string{s.blobDir, s.refDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := removeTempFiles(basePath, 0); err != nil {
+		return nil, fmt.Errorf("cleanup temp files failed: %w", err)
+	}
+	if err := s.rebuildRefCount(); err != nil {
+		return nil, fmt.Errorf("rebuild blob references failed: %w", err)
+	}
+	return s, nil
+}
+
+func (s *BlobStorage) Read(path string) (*Content, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ref, stat, err := s.readRef(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已打开的文件在内容被回收后仍可完整读取
+	file, err := os.Open(s.blobPath(ref.Digest))
+	if err != nil {
+		return nil, fmt.Errorf("open blob %s for %s failed: %w", ref.Digest, path, err)
+	}
+
+	return &Content{
+		ReadCloser:  file,
+		Size:        ref.Size,
+		ContentType: getContentType(path),
+		ModTime:     stat.ModTime(),
+	}, nil
+}
+
+func (s *BlobStorage) Write(path string, r io.Reader, size int64) error {
+	refPath, err := s.refPath(path)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件并计算 digest，不持有全局锁
+	tmp, err := os.CreateTemp(s.blobDir, ".blob"+tempFileMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	hasher, sha1Hasher := sha256.New(), sha1.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher, sha1Hasher), r)
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("write %s: expected %d bytes, got %d", path, size, written)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 内容已存在时直接复用
+	blobPath := s.blobPath(digest)
+	if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blobPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, blobPath); err != nil {
+			return err
+		}
+		syncDir(filepath.Dir(blobPath))
+	} else if err != nil {
+		return err
+	}
+
+	oldRef, _, err := s.readRef(path)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	data, err := json.Marshal(&blobRef{
+		Digest: digest,
+		SHA1:   hex.EncodeToString(sha1Hasher.Sum(nil)),
+		Size:   written,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.locks, refPath, bytes.NewReader(data), int64(len(data))); err != nil {
+		// 新内容没有被引用时回收
+		if s.refCount[digest] == 0 {
+			os.Remove(blobPath)
+		}
+		return err
+	}
+
+	s.refCount[digest]++
+	if oldRef != nil {
+		s.release(oldRef.Digest)
+	}
+	return nil
+}
+
+func (s *BlobStorage) List(path string) ([]FileInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	refPath, err := s.refPath(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(refPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfos := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if isTempFile(entry.Name()) {
+			continue
+		}
+
+		info := FileInfo{
+			Name:    entry.Name(),
+			ModTime: entry.ModTime(),
+			IsDir:   entry.IsDir(),
+		}
+		if !entry.IsDir() {
+			// 引用文件的大小不是内容大小，需要读取引用
+			ref, _, err := s.readRef(filepath.Join(path, entry.Name()))
+			if err != nil {
+				continue
+			}
+			info.Size = ref.Size
+		}
+		fileInfos = append(fileInfos, info)
+	}
+
+	return fileInfos, nil
+}
+
+func (s *BlobStorage) Exists(path string) bool {
+	refPath, err := s.refPath(path)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(refPath)
+	return err == nil
+}
+
+func (s *BlobStorage) Stat(path string) (*FileInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	refPath, err := s.refPath(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(refPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, err
+	}
+	if stat.IsDir() {
+		return &FileInfo{Name: stat.Name(), ModTime: stat.ModTime(), IsDir: true}, nil
+	}
+
+	ref, err := readRefFile(refPath)
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfo{
+		Name:        stat.Name(),
+		Size:        ref.Size,
+		ModTime:     stat.ModTime(),
+		ContentType: getContentType(path),
+		SHA1:        ref.SHA1,
+		SHA256:      ref.Digest,
+	}, nil
+}
+
+func (s *BlobStorage) Delete(path string) error {
+	fullPath, err := s.refPath(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(fullPath) == filepath.Clean(s.refDir) {
+		return fmt.Errorf("refuse to delete storage root")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return err
+	}
+
+	// 收集需要释放的引用
+	digests := []string{}
+	if stat.IsDir() {
+		err = filepath.Walk(fullPath, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || isTempFile(info.Name()) {
+				return err
+			}
+			ref, err := readRefFile(p)
+			if err != nil {
+				return err
+			}
+			digests = append(digests, ref.Digest)
+			return nil
+		})
+	} else {
+		var ref *blobRef
+		if ref, err = readRefFile(fullPath); err == nil {
+			digests = append(digests, ref.Digest)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(fullPath); err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		s.release(digest)
+	}
+
+	// 向上清理空目录
+	for dir := filepath.Dir(fullPath); dir != s.refDir && strings.HasPrefix(dir, s.refDir); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Usage 返回去重后实际占用的字节数和内容数量
+func (s *BlobStorage) Usage() (int64, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int64
+	for digest := range s.refCount {
+		stat, err := os.Stat(s.blobPath(digest))
+		if err != nil {
+			return 0, 0, err
+		}
+		total += stat.Size()
+	}
+	return total, len(s.refCount), nil
+}
+
+// release 减少引用计数，无引用时删除内容，调用方需持有写锁
+func (s *BlobStorage) release(digest string) {
+	s.refCount[digest]--
+	if s.refCount[digest] > 0 {
+		return
+	}
+	delete(s.refCount, digest)
+	os.Remove(s.blobPath(digest))
+}
+
+// rebuildRefCount 遍历所有引用重建计数，并删除无引用的内容
+func (s *BlobStorage) rebuildRefCount() error {
+	err := filepath.Walk(s.refDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ref, err := readRefFile(p)
+		if err != nil {
+			return err
+		}
+		s.refCount[ref.Digest]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(s.blobDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if s.refCount[info.Name()] == 0 {
+			return os.Remove(p)
+		}
+		return nil
+	})
+}
+
+// readRef 读取路径对应的引用
+func (s *BlobStorage) readRef(path string) (*blobRef, os.FileInfo, error) {
+	fullPath, err := s.refPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		return nil, nil, fmt.Errorf("%w: %s is a directory", ErrNotFound, path)
+	}
+
+	ref, err := readRefFile(fullPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, stat, nil
+}
+
+func readRefFile(fullPath string) (*blobRef, error) {
+	data, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &blobRef{}
+	if err := json.Unmarshal(data, ref); err != nil {
+		return nil, fmt.Errorf("parse blob reference %s failed: %w", fullPath, err)
+	}
+	return ref, nil
+}
+
+func (s *BlobStorage) refPath(path string) (string, error) {
+	return resolvePath(s.refDir, path)
+}
+
+func (s *BlobStorage) blobPath(digest string) string {
+	return filepath.Join(s.blobDir, digest[:2], digest)
+}
@@ -0,0 +1,394 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 加密文件格式：
+//
+//	header: magic(4) | 密钥 ID 长度(1) | 密钥 ID，补齐到 32 字节 | nonce 前缀(7)
+//	body:   按 64KB 明文分块的 AES-GCM 密文，每块附带 16 字节认证标签
+//
+// 每块的 nonce 为 nonce 前缀 + 块序号(4) + 末块标记(1)，header 作为附加数据参与认证，
+// 可以检测块的重排、截断以及密钥 ID 被篡改。头部定长，因此可由密文长度直接算出明文长度。
+const (
+	encMagic        = "MPE1"
+	encMaxKeyID     = 32
+	encNoncePrefix  = 7
+	encHeaderSize   = len(encMagic) + 1 + encMaxKeyID + encNoncePrefix
+	encChunkSize    = 64 * 1024
+	encTagSize      = 16
+	encSealedChunk  = encChunkSize + encTagSize
+	encKeySize      = 32
+	encLastChunkBit = 1
+)
+
+// ErrDecrypt 文件无法解密或已被篡改
+var ErrDecrypt = errors.New("decrypt failed")
+
+// Keyring 加密密钥集合，新写入使用当前密钥，读取时按文件头中的密钥 ID 选择
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// ParseKeyring 解析密钥，每行或每个逗号分隔项为 <keyID>:<base64 编码的 32 字节密钥>
+// active 为空时使用第一个密钥加密新文件
+func ParseKeyring(text string, active string) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	items := strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	})
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid key entry, expect <id>:<base64 key>")
+		}
+		id := strings.TrimSpace(parts[0])
+		if id == "" || len(id) > encMaxKeyID {
+			return nil, fmt.Errorf("key id must be 1-%d bytes: %q", encMaxKeyID, id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("decode key %s failed: %w", id, err)
+		}
+		if len(key) != encKeySize {
+			return nil, fmt.Errorf("key %s must be %d bytes, got %d", id, encKeySize, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		ring.keys[id] = aead
+		if ring.active == "" {
+			ring.active = id
+		}
+	}
+
+	if len(ring.keys) == 0 {
+		return nil, errors.New("no encryption key configured")
+	}
+	if active != "" {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, fmt.Errorf("active key %s not found", active)
+		}
+		ring.active = active
+	}
+	return ring, nil
+}
+
+// ActiveKey 返回加密新文件使用的密钥 ID
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// EncryptedStorage 使用 AES-GCM 加密文件内容的存储包装器
+// 对上层完全透明，读取和列表返回的都是明文长度
+type EncryptedStorage struct {
+	base    Storage
+	keyring *Keyring
+}
+
+// NewEncryptedStorage 创建加密存储包装器
+func NewEncryptedStorage(base Storage, keyring *Keyring) *EncryptedStorage {
+	return &EncryptedStorage{
+		base:    base,
+		keyring: keyring,
+	}
+}
+
+func (s *EncryptedStorage) Read(path string) (*Content, error) {
+	content, err := s.base.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.newDecryptReader(content)
+	if err != nil {
+		content.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &Content{
+		ReadCloser:  reader,
+		Size:        plaintextSize(content.Size),
+		ContentType: content.ContentType,
+		ModTime:     content.ModTime,
+	}, nil
+}
+
+func (s *EncryptedStorage) Write(path string, r io.Reader, size int64) error {
+	reader, err := s.newEncryptReader(r)
+	if err != nil {
+		return err
+	}
+	return s.base.Write(path, reader, ciphertextSize(size))
+}
+
+func (s *EncryptedStorage) List(path string) ([]FileInfo, error) {
+	entries, err := s.base.List(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if !entries[i].IsDir {
+			entries[i].Size = plaintextSize(entries[i].Size)
+		}
+	}
+	return entries, nil
+}
+
+func (s *EncryptedStorage) Exists(path string) bool {
+	return s.base.Exists(path)
+}
+
+// Stat 返回明文长度，底层记录的是密文摘要，因此不返回摘要
+func (s *EncryptedStorage) Stat(path string) (*FileInfo, error) {
+	info, err := s.base.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir {
+		info.Size = plaintextSize(info.Size)
+		info.SHA1 = ""
+		info.SHA256 = ""
+	}
+	return info, nil
+}
+
+func (s *EncryptedStorage) Delete(path string) error {
+	return s.base.Delete(path)
+}
+
+func (s *EncryptedStorage) Touch(path string) {
+	Touch(s.base, path)
+}
+
+func (s *EncryptedStorage) LockPath(path string) (func(), error) {
+	return LockPath(s.base, path)
+}
+
+// plaintextSize 由密文长度计算明文长度，未知时返回 -1
+func plaintextSize(size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	body := size - int64(encHeaderSize)
+	if body < encTagSize {
+		return 0
+	}
+	chunks := (body + encSealedChunk - 1) / encSealedChunk
+	return body - chunks*encTagSize
+}
+
+// ciphertextSize 由明文长度计算密文长度，未知时返回 -1
+func ciphertextSize(size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	chunks := (size + encChunkSize - 1) / encChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(encHeaderSize) + size + chunks*encTagSize
+}
+
+// chunkNonce 生成分块的 nonce
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	if last {
+		return append(nonce, encLastChunkBit)
+	}
+	return append(nonce, 0)
+}
+
+// encryptReader 将明文流转换为密文流
+type encryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint32
+
+	cur  []byte // 待加密的当前块
+	next []byte // 预读的下一块，用于判断当前块是否为末块
+	out  bytes.Buffer
+	done bool
+	err  error
+}
+
+func (s *EncryptedStorage) newEncryptReader(src io.Reader) (*encryptReader, error) {
+	id := s.keyring.active
+
+	header := make([]byte, 0, encHeaderSize)
+	header = append(header, encMagic...)
+	header = append(header, byte(len(id)))
+	header = append(header, id...)
+	header = append(header, make([]byte, encMaxKeyID-len(id))...)
+	prefix := make([]byte, encNoncePrefix)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header = append(header, prefix...)
+
+	r := &encryptReader{
+		src:    src,
+		aead:   s.keyring.keys[id],
+		header: header,
+		prefix: prefix,
+	}
+	r.out.Write(header)
+
+	r.cur, r.err = readChunk(src)
+	return r, nil
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		// 当前块不满时一定是末块，否则预读下一块判断
+		last := len(r.cur) < encChunkSize
+		if !last {
+			r.next, r.err = readChunk(r.src)
+			if r.err != nil {
+				return 0, r.err
+			}
+			last = len(r.next) == 0
+		}
+
+		nonce := chunkNonce(r.prefix, r.index, last)
+		r.out.Write(r.aead.Seal(nil, nonce, r.cur, r.header))
+		r.index++
+
+		r.cur, r.next = r.next, nil
+		r.done = last
+	}
+	return r.out.Read(p)
+}
+
+// readChunk 读取一个完整分块，返回的长度小于分块大小说明已到结尾
+func readChunk(src io.Reader) ([]byte, error) {
+	buf := make([]byte, encChunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+// decryptReader 将密文流还原为明文流
+type decryptReader struct {
+	src    *bufio.Reader
+	closer io.Closer
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint32
+	buf    []byte
+	out    []byte
+	done   bool
+}
+
+func (s *EncryptedStorage) newDecryptReader(content *Content) (*decryptReader, error) {
+	src := bufio.NewReaderSize(content, encSealedChunk)
+
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("%w: read header: %v", ErrDecrypt, err)
+	}
+	if string(header[:len(encMagic)]) != encMagic {
+		return nil, fmt.Errorf("%w: not an encrypted file", ErrDecrypt)
+	}
+
+	idLen := int(header[len(encMagic)])
+	if idLen == 0 || idLen > encMaxKeyID {
+		return nil, fmt.Errorf("%w: invalid key id", ErrDecrypt)
+	}
+	idStart := len(encMagic) + 1
+	id := string(header[idStart : idStart+idLen])
+	aead, ok := s.keyring.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %s", ErrDecrypt, id)
+	}
+
+	return &decryptReader{
+		src:    src,
+		closer: content,
+		aead:   aead,
+		header: header,
+		prefix: header[idStart+encMaxKeyID:],
+		buf:    make([]byte, encSealedChunk),
+	}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				err = fmt.Errorf("%w: truncated file", ErrDecrypt)
+			}
+			return 0, err
+		}
+
+		// 没有后续数据时当前块应为末块
+		last := err == io.ErrUnexpectedEOF
+		if !last {
+			if _, peekErr := r.src.Peek(1); peekErr == io.EOF {
+				last = true
+			}
+		}
+
+		plain, openErr := r.aead.Open(r.buf[:0], chunkNonce(r.prefix, r.index, last), r.buf[:n], r.header)
+		if openErr != nil {
+			return 0, fmt.Errorf("%w: chunk %d: %v", ErrDecrypt, r.index, openErr)
+		}
+		r.index++
+		r.out = plain
+		r.done = last
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
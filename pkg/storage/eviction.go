@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// companionSuffixes 随构件一起淘汰的附属文件后缀
+var companionSuffixes = []string{".md5", ".sha1", ".sha256", ".sha512", ".asc"}
+
+// EvictingStorage 限制缓存总大小的存储包装器，超出上限时由后台按最后访问时间淘汰文件，
+// 构件与其校验和、签名文件作为一组淘汰。访问时间由写入和客户端访问（Touch）更新，读取和写入不等待淘汰
+//
+// 访问时间只记录在内存中，重启后以文件修改时间为准
+type EvictingStorage struct {
+	base     Storage
+	maxSize  int64
+	lowWater int64 // 淘汰到该大小以下为止
+	interval time.Duration
+
+	mu         sync.Mutex
+	lastAccess map[string]time.Time
+	size       int64 // 估算的当前大小，每次淘汰时重新统计
+	lastRun    time.Time
+
+	evicted      uint64
+	evictedBytes uint64
+	wake         chan struct{}
+	stopChan     chan struct{}
+}
+
+// EvictionStats 缓存淘汰统计信息
+type EvictionStats struct {
+	Size         int64     `json:"size"`
+	MaxSize      int64     `json:"maxSize"`
+	Evicted      uint64    `json:"evicted"`
+	EvictedBytes uint64    `json:"evictedBytes"`
+	LastRun      time.Time `json:"lastRun"`
+}
+
+// evictionGroup 一起淘汰的构件及附属文件
+type evictionGroup struct {
+	paths  []string
+	size   int64
+	access time.Time
+}
+
+// NewEvictingStorage 创建缓存淘汰包装器，lowWatermark 为每次淘汰后保留的大小占上限的百分比
+func NewEvictingStorage(base Storage, maxSize int64, lowWatermark int, interval time.Duration) *EvictingStorage {
+	if lowWatermark <= 0 || lowWatermark > 100 {
+		lowWatermark = 100
+	}
+	return &EvictingStorage{
+		base:       base,
+		maxSize:    maxSize,
+		lowWater:   maxSize * int64(lowWatermark) / 100,
+		interval:   interval,
+		lastAccess: make(map[string]time.Time),
+		wake:       make(chan struct{}, 1),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Read 不记录访问，完整性校验、导出和索引重建的读取不会让文件免于淘汰
+func (s *EvictingStorage) Read(path string) (*Content, error) {
+	return s.base.Read(path)
+}
+
+func (s *EvictingStorage) Write(path string, r io.Reader, size int64) error {
+	counter := &countingReader{r: r}
+	if err := s.base.Write(path, counter, size); err != nil {
+		return err
+	}
+	s.touch(path)
+
+	// 覆盖写入时会高估，下次淘汰时重新统计
+	s.mu.Lock()
+	s.size += counter.n
+	over := s.size > s.maxSize
+	s.mu.Unlock()
+
+	if over {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *EvictingStorage) List(path string) ([]FileInfo, error) {
+	return s.base.List(path)
+}
+
+func (s *EvictingStorage) Exists(path string) bool {
+	return s.base.Exists(path)
+}
+
+func (s *EvictingStorage) Stat(path string) (*FileInfo, error) {
+	return s.base.Stat(path)
+}
+
+func (s *EvictingStorage) Delete(path string) error {
+	if err := s.base.Delete(path); err != nil {
+		return err
+	}
+
+	key := tierKey(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lastAccess, key)
+	for p := range s.lastAccess {
+		if strings.HasPrefix(p, key+"/") {
+			delete(s.lastAccess, p)
+		}
+	}
+	return nil
+}
+
+// Touch 记录客户端访问并转发给底层存储
+func (s *EvictingStorage) Touch(path string) {
+	s.touch(path)
+	Touch(s.base, path)
+}
+
+func (s *EvictingStorage) LockPath(path string) (func(), error) {
+	return LockPath(s.base, path)
+}
+
+// Start 启动后台淘汰，启动时先统计一次当前大小
+func (s *EvictingStorage) Start() {
+	go func() {
+		s.runEviction()
+
+		var tick <-chan time.Time
+		if s.interval > 0 {
+			ticker := time.NewTicker(s.interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-tick:
+			case <-s.wake:
+			case <-s.stopChan:
+				return
+			}
+			s.runEviction()
+		}
+	}()
+}
+
+// Stop 停止后台淘汰
+func (s *EvictingStorage) Stop() {
+	close(s.stopChan)
+}
+
+// Stats 返回淘汰统计
+func (s *EvictingStorage) Stats() EvictionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return EvictionStats{
+		Size:         s.size,
+		MaxSize:      s.maxSize,
+		Evicted:      atomic.LoadUint64(&s.evicted),
+		EvictedBytes: atomic.LoadUint64(&s.evictedBytes),
+		LastRun:      s.lastRun,
+	}
+}
+
+func (s *EvictingStorage) runEviction() {
+	evicted, err := s.Evict()
+	if err != nil {
+		log.Warnf("evict cache failed: %v", err)
+	} else if evicted > 0 {
+		log.Infof("evicted %d cached files", evicted)
+	}
+}
+
+// Evict 统计当前大小，超出上限时淘汰最久未访问的文件，返回删除的文件数
+func (s *EvictingStorage) Evict() (int, error) {
+	start := time.Now()
+	groups := make(map[string]*evictionGroup)
+	total := int64(0)
+
+	err := Walk(s.base, "/", func(p string, info FileInfo) error {
+		total += info.Size
+		owner := p
+		for _, suffix := range companionSuffixes {
+			if strings.HasSuffix(p, suffix) {
+				owner = strings.TrimSuffix(p, suffix)
+				break
+			}
+		}
+
+		group, ok := groups[owner]
+		if !ok {
+			group = &evictionGroup{}
+			groups[owner] = group
+		}
+		group.paths = append(group.paths, p)
+		group.size += info.Size
+		if access := s.accessTime(p, info.ModTime); access.After(group.access) {
+			group.access = access
+		}
+		return nil
+	})
+	if err != nil && s.base.Exists("/") {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.size = total
+	s.lastRun = start
+	s.mu.Unlock()
+
+	if total <= s.maxSize {
+		return 0, nil
+	}
+
+	ordered := make([]*evictionGroup, 0, len(groups))
+	for _, group := range groups {
+		ordered = append(ordered, group)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].access.Before(ordered[j].access)
+	})
+
+	evicted := 0
+	for _, group := range ordered {
+		if total <= s.lowWater {
+			break
+		}
+		// 统计期间被访问过的文件保留
+		if s.accessedSince(group.paths, start) {
+			continue
+		}
+
+		for _, p := range group.paths {
+			if err := s.base.Delete(p); err != nil && !errors.Is(err, ErrNotFound) {
+				log.Warnf("evict %s failed: %v", p, err)
+				continue
+			}
+			s.mu.Lock()
+			delete(s.lastAccess, tierKey(p))
+			s.mu.Unlock()
+			evicted++
+			atomic.AddUint64(&s.evicted, 1)
+		}
+		total -= group.size
+		atomic.AddUint64(&s.evictedBytes, uint64(group.size))
+	}
+
+	s.mu.Lock()
+	s.size = total
+	s.mu.Unlock()
+	return evicted, nil
+}
+
+// touch 记录访问时间
+func (s *EvictingStorage) touch(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAccess[tierKey(path)] = time.Now()
+}
+
+// accessTime 返回最后访问时间，没有访问记录时使用修改时间
+func (s *EvictingStorage) accessTime(path string, modTime time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.lastAccess[tierKey(path)]; ok && t.After(modTime) {
+		return t
+	}
+	return modTime
+}
+
+// accessedSince 判断一组文件在指定时间之后是否被访问过
+func (s *EvictingStorage) accessedSince(paths []string, since time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range paths {
+		if t, ok := s.lastAccess[tierKey(p)]; ok && t.After(since) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictingStorageOnlyTouchRecordsAccess(t *testing.T) {
+	s := NewEvictingStorage(NewFileSystemStorage(t.TempDir()), 6, 100, 0)
+
+	for _, p := range []string{"/a/a-1.0.jar", "/b/b-1.0.jar"} {
+		if err := WriteBytes(s, p, []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 客户端访问 a 后，后台任务读取 b 不应让 b 比 a 更晚被淘汰
+	s.Touch("/a/a-1.0.jar")
+	time.Sleep(10 * time.Millisecond)
+	if _, err := ReadBytes(s, "/b/b-1.0.jar"); err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted, err := s.Evict(); err != nil || evicted != 1 {
+		t.Fatalf("Evict = %d, %v", evicted, err)
+	}
+	if !s.Exists("/a/a-1.0.jar") || s.Exists("/b/b-1.0.jar") {
+		t.Errorf("evicted wrong file: a=%v b=%v", s.Exists("/a/a-1.0.jar"), s.Exists("/b/b-1.0.jar"))
+	}
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lockSuffix 锁文件后缀，文件 foo.jar 的锁文件为 .foo.jar.lock
+const lockSuffix = ".lock"
+
+// ErrLockTimeout 等待其他实例释放锁超时
+var ErrLockTimeout = errors.New("timeout waiting for lock")
+
+// LockOptions 锁文件选项
+type LockOptions struct {
+	TTL     time.Duration // 租约有效期，持有者每隔 TTL/3 续约，超过 TTL 没有续约的锁视为失效
+	Timeout time.Duration // 等待锁的最长时间
+}
+
+// lockOwner 锁文件内容，用于释放时确认锁仍属于自己
+type lockOwner struct {
+	Owner    string    `json:"owner"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// fileLocker 基于锁文件的跨进程互斥，用于多个实例共享同一个网络文件系统
+//
+// 锁文件以 O_EXCL 创建，持有期间定期更新修改时间作为租约。
+// 持有者异常退出后租约过期，其他实例将锁文件改名后删除，改名保证只有一个实例能删除同一个失效的锁
+type fileLocker struct {
+	owner string
+	opts  LockOptions
+}
+
+func newFileLocker(opts LockOptions) *fileLocker {
+	if opts.TTL <= 0 {
+		opts.TTL = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * opts.TTL
+	}
+
+	hostname, _ := os.Hostname()
+	return &fileLocker{
+		owner: fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), randomSuffix()),
+		opts:  opts,
+	}
+}
+
+// Lock 获取文件的锁，返回解锁函数
+func (l *fileLocker) Lock(fullPath string) (func(), error) {
+	lockPath := lockFilePath(fullPath)
+	deadline := time.Now().Add(l.opts.Timeout)
+	wait := 10 * time.Millisecond
+
+	for {
+		err := l.create(lockPath)
+		if err == nil {
+			return l.hold(lockPath), nil
+		}
+		// 父目录可能刚被其他实例清理，重试即可
+		if !os.IsExist(err) && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if os.IsExist(err) && l.breakStale(lockPath) {
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLockTimeout, fullPath)
+		}
+		time.Sleep(wait)
+		if wait < 500*time.Millisecond {
+			wait *= 2
+		}
+	}
+}
+
+// create 以独占方式创建锁文件
+func (l *fileLocker) create(lockPath string) error {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(lockOwner{Owner: l.owner, Acquired: time.Now()})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(lockPath)
+		return err
+	}
+	return nil
+}
+
+// hold 在后台续约，返回解锁函数
+func (l *fileLocker) hold(lockPath string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(l.opts.TTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now()
+				if err := os.Chtimes(lockPath, now, now); err != nil {
+					log.Warnf("renew lock %s failed: %v", lockPath, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		// 租约过期后锁可能已被其他实例获取，此时不能删除
+		if l.owns(lockPath) {
+			os.Remove(lockPath)
+		} else {
+			log.Warnf("lock %s was taken over before release, lease expired", lockPath)
+		}
+	}
+}
+
+// owns 判断锁文件是否属于当前实例
+func (l *fileLocker) owns(lockPath string) bool {
+	data, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	owner := lockOwner{}
+	return json.Unmarshal(data, &owner) == nil && owner.Owner == l.owner
+}
+
+// breakStale 删除过期的锁文件，返回是否删除
+func (l *fileLocker) breakStale(lockPath string) bool {
+	if !l.isStale(lockPath) {
+		return false
+	}
+
+	// 先改名再确认，避免删除其他实例刚刚重新创建的锁
+	stalePath := lockPath + tempFileMarker + randomSuffix()
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		return false
+	}
+	if !l.isStale(stalePath) {
+		// 改名的是新创建的锁，尽量还原，目标已存在时 Link 失败
+		os.Link(stalePath, lockPath)
+		os.Remove(stalePath)
+		return false
+	}
+
+	os.Remove(stalePath)
+	log.Warnf("removed stale lock %s", lockPath)
+	return true
+}
+
+func (l *fileLocker) isStale(lockPath string) bool {
+	stat, err := os.Stat(lockPath)
+	return err == nil && time.Since(stat.ModTime()) > l.opts.TTL
+}
+
+// lockFilePath 返回文件对应的锁文件路径
+func lockFilePath(fullPath string) string {
+	return filepath.Join(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+lockSuffix)
+}
+
+// isLockFile 判断是否为锁文件
+func isLockFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, lockSuffix)
+}
+
+func randomSuffix() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockPathAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	opts := LockOptions{TTL: time.Minute, Timeout: 50 * time.Millisecond}
+	a := NewFileSystemStorage(dir)
+	a.EnableFileLocks(opts)
+	b := NewFileSystemStorage(dir)
+	b.EnableFileLocks(opts)
+
+	if err := WriteBytes(a, "/com/ex/lib/1.0/lib-1.0.jar", []byte("jar")); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := LockPath(NewPrefixedStorage(a, "/com"), "/ex/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 持有目录锁期间仍然可以写入目录下的文件
+	if err := WriteBytes(a, "/com/ex/lib/maven-metadata.xml", []byte("<metadata/>")); err != nil {
+		t.Fatalf("write while holding lock: %v", err)
+	}
+	if _, err := b.LockPath("/com/ex/lib"); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("second instance LockPath = %v, want ErrLockTimeout", err)
+	}
+	if entries, err := a.List("/com/ex"); err != nil || listNames(entries) != "lib/" {
+		t.Errorf("List = %q, %v, lock file must be hidden", listNames(entries), err)
+	}
+	unlock()
+
+	unlock, err = b.LockPath("/com/ex/lib")
+	if err != nil {
+		t.Fatalf("LockPath after release: %v", err)
+	}
+	unlock()
+
+	if _, err := a.LockPath("/"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("LockPath root = %v, want ErrInvalidPath", err)
+	}
+	// 未启用锁文件时直接返回
+	if unlock, err := LockPath(NewFileSystemStorage(dir), "/com/ex/lib"); err != nil {
+		t.Errorf("LockPath without file locks: %v", err)
+	} else {
+		unlock()
+	}
+}
@@ -1,48 +1,137 @@
-// pkg/storage/filesystem.go
 package storage
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// tempFileMarker 临时文件名标记，写入中的文件命名为 .<name>.tmp-<random>
+const tempFileMarker = ".tmp-"
+
 type FileSystemStorage struct {
-	basePath string
+	basePath  string
+	locks     *pathLocker
+	fileLocks *fileLocker // 多实例共享目录时的跨进程锁，为空时只在进程内互斥
 }
 
 func NewFileSystemStorage(basePath string) *FileSystemStorage {
-	return &FileSystemStorage{basePath: basePath}
+	return &FileSystemStorage{
+		basePath: basePath,
+		locks:    newPathLocker(),
+	}
+}
+
+// EnableFileLocks 启用锁文件，写入、删除以及属性文件更新在多个实例之间互斥
+func (s *FileSystemStorage) EnableFileLocks(opts LockOptions) {
+	s.fileLocks = newFileLocker(opts)
 }
 
-func (s *FileSystemStorage) Read(path string) ([]byte, int, http.Header, error) {
-	fullPath := filepath.Join(s.basePath, path)
-	data, err := ioutil.ReadFile(fullPath)
+// lockShared 获取跨进程锁，未启用锁文件时直接返回
+func (s *FileSystemStorage) lockShared(fullPath string) (func(), error) {
+	if s.fileLocks == nil {
+		return func() {}, nil
+	}
+	return s.fileLocks.Lock(fullPath)
+}
+
+// LockPath 获取路径的跨进程锁，锁文件与文件自身的写入锁不同，可以锁目录，也可以在持有期间写入该路径
+func (s *FileSystemStorage) LockPath(path string) (func(), error) {
+	fullPath, err := resolvePath(s.basePath, path)
 	if err != nil {
-		return nil, 404, nil, err
+		return nil, err
 	}
+	// 根目录的锁文件会落在存储目录之外
+	if fullPath == filepath.Clean(s.basePath) {
+		return nil, fmt.Errorf("%w: cannot lock root", ErrInvalidPath)
+	}
+	return s.lockShared(fullPath + lockSuffix)
+}
 
-	headers := http.Header{
-		"Content-Type": []string{getContentType(path)},
+func (s *FileSystemStorage) Read(path string) (*Content, error) {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
 	}
 
-	return data, 200, headers, nil
+	// 持有读锁打开文件，打开后即使被替换也能读到完整的旧内容
+	unlock := s.locks.RLock(fullPath)
+	file, err := os.Open(fullPath)
+	unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s is a directory", ErrNotFound, path)
+	}
+
+	return &Content{
+		ReadCloser:  file,
+		Size:        stat.Size(),
+		ContentType: getContentType(path),
+		ModTime:     stat.ModTime(),
+	}, nil
 }
 
-func (s *FileSystemStorage) Write(path string, data []byte) error {
-	fullPath := filepath.Join(s.basePath, path)
+// Write 先写入同目录下的临时文件，同步到磁盘后再重命名到目标路径，
+// 崩溃或中断的写入不会留下不完整的文件。写入的同时计算摘要并保存到属性文件
+//
+// 启用锁文件时，重命名和写入属性文件在锁内完成，多个实例同时写入同一路径时文件与属性保持一致
+func (s *FileSystemStorage) Write(path string, r io.Reader, size int64) error {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return err
+	}
+
+	digest := newDigestReader(r)
+	tmpPath, err := writeTempFile(fullPath, digest, size)
+	if err != nil {
+		return err
+	}
+
+	unlockShared, err := s.lockShared(fullPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	defer unlockShared()
 
-	// 创建父目录
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+	if err := commitTempFile(s.locks, tmpPath, fullPath); err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(fullPath, data, 0o644)
+	// 属性文件只是加速手段，写入失败时 Stat 退化为不带摘要
+	stat, err := os.Stat(fullPath)
+	if err == nil {
+		err = s.writeAttributes(fullPath, digest.attributes(path, stat.ModTime()))
+	}
+	if err != nil {
+		log.Warnf("write attributes of %s failed: %v", path, err)
+	}
+	return nil
 }
 
 func (s *FileSystemStorage) List(path string) ([]FileInfo, error) {
-	fullPath := filepath.Join(s.basePath, path)
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
+	}
 
 	// 读取目录内容
 	entries, err := ioutil.ReadDir(fullPath)
@@ -50,9 +139,12 @@ func (s *FileSystemStorage) List(path string) ([]FileInfo, error) {
 		return nil, err
 	}
 
-	// 转换为 FileInfo 列表
+	// 转换为 FileInfo 列表，忽略写入中的临时文件和属性文件
 	fileInfos := make([]FileInfo, 0, len(entries))
 	for _, entry := range entries {
+		if isHiddenFile(entry.Name()) {
+			continue
+		}
 		fileInfos = append(fileInfos, FileInfo{
 			Name:    entry.Name(),
 			Size:    entry.Size(),
@@ -65,11 +157,227 @@ func (s *FileSystemStorage) List(path string) ([]FileInfo, error) {
 }
 
 func (s *FileSystemStorage) Exists(path string) bool {
-	fullPath := filepath.Join(s.basePath, path)
-	_, err := os.Stat(fullPath)
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(fullPath)
 	return err == nil
 }
 
+func (s *FileSystemStorage) Stat(path string) (*FileInfo, error) {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, err
+	}
+
+	info := &FileInfo{
+		Name:    stat.Name(),
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+		IsDir:   stat.IsDir(),
+	}
+	if stat.IsDir() {
+		info.Size = 0
+		return info, nil
+	}
+
+	info.ContentType = getContentType(path)
+	if data, err := ioutil.ReadFile(attributesPath(fullPath)); err == nil {
+		if attrs, err := parseAttributes(data, stat.Size(), stat.ModTime()); err == nil {
+			info.SHA1 = attrs.SHA1
+			info.SHA256 = attrs.SHA256
+		}
+	}
+	return info, nil
+}
+
+func (s *FileSystemStorage) Delete(path string) error {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return err
+	}
+	if filepath.Clean(fullPath) == filepath.Clean(s.basePath) {
+		return fmt.Errorf("refuse to delete storage root")
+	}
+
+	if err := s.remove(path, fullPath); err != nil {
+		return err
+	}
+
+	// 锁文件位于父目录中，释放锁之后才能清理空目录
+	s.removeEmptyParents(filepath.Dir(fullPath))
+	return nil
+}
+
+// remove 在锁内删除文件或目录及其属性文件
+func (s *FileSystemStorage) remove(path, fullPath string) error {
+	unlockShared, err := s.lockShared(fullPath)
+	if err != nil {
+		return err
+	}
+	defer unlockShared()
+
+	unlock := s.locks.Lock(fullPath)
+	defer unlock()
+
+	if _, err := os.Lstat(fullPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return err
+	}
+	if err := os.RemoveAll(fullPath); err != nil {
+		return err
+	}
+	os.Remove(attributesPath(fullPath))
+	return nil
+}
+
+// writeAttributes 写入文件的属性文件
+func (s *FileSystemStorage) writeAttributes(fullPath string, attrs *attributes) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.locks, attributesPath(fullPath), bytes.NewReader(data), int64(len(data)))
+}
+
+// attributesPath 返回文件对应的属性文件路径
+func attributesPath(fullPath string) string {
+	return filepath.Join(filepath.Dir(fullPath), attributesName(filepath.Base(fullPath)))
+}
+
+// removeEmptyParents 向上删除空目录，直到存储根目录
+func (s *FileSystemStorage) removeEmptyParents(dir string) {
+	root := filepath.Clean(s.basePath)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		// 目录非空时 Remove 失败，停止向上清理
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// CleanupTempFiles 删除异常退出遗留的临时文件和锁文件，应在启动时调用
+//
+// olderThan 为 0 时删除所有临时文件；多个实例共享目录时其他实例可能正在写入，
+// 应只删除超过一定时间没有修改的文件
+func (s *FileSystemStorage) CleanupTempFiles(olderThan time.Duration) (int, error) {
+	return removeTempFiles(s.basePath, olderThan)
+}
+
+// removeTempFiles 递归删除目录下超过 olderThan 没有修改的临时文件和锁文件
+func removeTempFiles(root string, olderThan time.Duration) (int, error) {
+	removed := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !(isTempFile(info.Name()) || isLockFile(info.Name())) {
+			return nil
+		}
+		if olderThan > 0 && time.Since(info.ModTime()) < olderThan {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// writeFileAtomic 通过临时文件加重命名的方式写入文件
+func writeFileAtomic(locks *pathLocker, fullPath string, r io.Reader, size int64) error {
+	tmpPath, err := writeTempFile(fullPath, r, size)
+	if err != nil {
+		return err
+	}
+	return commitTempFile(locks, tmpPath, fullPath)
+}
+
+// writeTempFile 写入目标文件同目录下的临时文件并同步到磁盘，返回临时文件路径
+func writeTempFile(fullPath string, r io.Reader, size int64) (string, error) {
+	dir := filepath.Dir(fullPath)
+
+	// 创建父目录，并发删除可能清理掉刚创建的空目录，此时重试一次
+	var tmp *os.File
+	for attempt := 0; ; attempt++ {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+
+		var err error
+		tmp, err = os.CreateTemp(dir, "."+filepath.Base(fullPath)+tempFileMarker+"*")
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) || attempt > 0 {
+			return "", err
+		}
+	}
+	tmpPath := tmp.Name()
+
+	written, err := io.Copy(tmp, r)
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("write %s: expected %d bytes, got %d", fullPath, size, written)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0o644)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// commitTempFile 将临时文件重命名到目标路径
+func commitTempFile(locks *pathLocker, tmpPath, fullPath string) error {
+	// 同一路径的提交互斥，读取方不会在替换过程中打开文件
+	unlock := locks.Lock(fullPath)
+	err := os.Rename(tmpPath, fullPath)
+	unlock()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	syncDir(filepath.Dir(fullPath))
+	return nil
+}
+
+// syncDir 同步目录项，保证重命名在崩溃后依然可见
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// isTempFile 判断是否为写入中的临时文件
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempFileMarker)
+}
+
 // getContentType 根据文件扩展名返回 MIME 类型
 func getContentType(filePath string) string {
 	ext := filepath.Ext(filePath)
@@ -0,0 +1,61 @@
+package storage
+
+import "sync"
+
+// pathLocker 按路径提供读写锁，锁在无人持有时自动回收
+type pathLocker struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.RWMutex
+	refs int
+}
+
+func newPathLocker() *pathLocker {
+	return &pathLocker{locks: make(map[string]*pathLock)}
+}
+
+// Lock 获取路径的写锁，返回解锁函数
+func (l *pathLocker) Lock(path string) func() {
+	lock := l.acquire(path)
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.release(path, lock)
+	}
+}
+
+// RLock 获取路径的读锁，返回解锁函数
+func (l *pathLocker) RLock(path string) func() {
+	lock := l.acquire(path)
+	lock.RLock()
+	return func() {
+		lock.RUnlock()
+		l.release(path, lock)
+	}
+}
+
+func (l *pathLocker) acquire(path string) *pathLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[path]
+	if !ok {
+		lock = &pathLock{}
+		l.locks[path] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+func (l *pathLocker) release(path string, lock *pathLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, path)
+	}
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryCacheStorage 在内存中缓存小文件的存储包装器
+// 按字节数限制总大小，超出时淘汰最久未访问的文件，写入和删除时失效
+type MemoryCacheStorage struct {
+	base        Storage
+	maxSize     int64
+	maxFileSize int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	version uint64 // 每次写入或删除递增，避免缓存写入前读到的旧内容
+
+	hits   uint64
+	misses uint64
+}
+
+// MemoryCacheStats 内存缓存统计信息
+type MemoryCacheStats struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Entries     int    `json:"entries"`
+	Size        int64  `json:"size"`
+	MaxSize     int64  `json:"maxSize"`
+	MaxFileSize int64  `json:"maxFileSize"`
+}
+
+type memoryCacheEntry struct {
+	path        string
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// NewMemoryCacheStorage 创建内存缓存包装器
+func NewMemoryCacheStorage(base Storage, maxSize, maxFileSize int64) *MemoryCacheStorage {
+	if maxFileSize > maxSize {
+		maxFileSize = maxSize
+	}
+	return &MemoryCacheStorage{
+		base:        base,
+		maxSize:     maxSize,
+		maxFileSize: maxFileSize,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+func (s *MemoryCacheStorage) Read(path string) (*Content, error) {
+	s.mu.Lock()
+	if elem, ok := s.entries[path]; ok {
+		s.lru.MoveToFront(elem)
+		entry := elem.Value.(*memoryCacheEntry)
+		s.mu.Unlock()
+
+		atomic.AddUint64(&s.hits, 1)
+		return &Content{
+			ReadCloser:  io.NopCloser(bytes.NewReader(entry.data)),
+			Size:        int64(len(entry.data)),
+			ContentType: entry.contentType,
+			ModTime:     entry.modTime,
+		}, nil
+	}
+	version := s.version
+	s.mu.Unlock()
+
+	atomic.AddUint64(&s.misses, 1)
+	content, err := s.base.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 大文件或长度未知的文件直接透传
+	if content.Size < 0 || content.Size > s.maxFileSize {
+		return content, nil
+	}
+
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	s.add(version, &memoryCacheEntry{
+		path:        path,
+		data:        data,
+		contentType: content.ContentType,
+		modTime:     content.ModTime,
+	})
+
+	return &Content{
+		ReadCloser:  io.NopCloser(bytes.NewReader(data)),
+		Size:        int64(len(data)),
+		ContentType: content.ContentType,
+		ModTime:     content.ModTime,
+	}, nil
+}
+
+func (s *MemoryCacheStorage) Write(path string, r io.Reader, size int64) error {
+	s.invalidate(path, false)
+	defer s.invalidate(path, false)
+	return s.base.Write(path, r, size)
+}
+
+func (s *MemoryCacheStorage) List(path string) ([]FileInfo, error) {
+	return s.base.List(path)
+}
+
+func (s *MemoryCacheStorage) Exists(path string) bool {
+	s.mu.Lock()
+	_, ok := s.entries[path]
+	s.mu.Unlock()
+	if ok {
+		return true
+	}
+	return s.base.Exists(path)
+}
+
+func (s *MemoryCacheStorage) Stat(path string) (*FileInfo, error) {
+	return s.base.Stat(path)
+}
+
+func (s *MemoryCacheStorage) Delete(path string) error {
+	// 删除目录时一并失效目录下的所有文件
+	s.invalidate(path, true)
+	defer s.invalidate(path, true)
+	return s.base.Delete(path)
+}
+
+// Touch 缓存命中的读取不经过底层存储，访问同样需要转发
+func (s *MemoryCacheStorage) Touch(path string) {
+	Touch(s.base, path)
+}
+
+func (s *MemoryCacheStorage) LockPath(path string) (func(), error) {
+	return LockPath(s.base, path)
+}
+
+// Stats 返回缓存命中统计
+func (s *MemoryCacheStorage) Stats() MemoryCacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return MemoryCacheStats{
+		Hits:        atomic.LoadUint64(&s.hits),
+		Misses:      atomic.LoadUint64(&s.misses),
+		Entries:     len(s.entries),
+		Size:        s.size,
+		MaxSize:     s.maxSize,
+		MaxFileSize: s.maxFileSize,
+	}
+}
+
+// add 加入缓存，期间发生过写入或删除时放弃
+func (s *MemoryCacheStorage) add(version uint64, entry *memoryCacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version != s.version {
+		return
+	}
+	if elem, ok := s.entries[entry.path]; ok {
+		s.remove(elem)
+	}
+
+	s.entries[entry.path] = s.lru.PushFront(entry)
+	s.size += int64(len(entry.data))
+
+	// 淘汰最久未访问的文件
+	for s.size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+}
+
+// invalidate 使路径失效，recursive 为 true 时同时失效其下所有路径
+func (s *MemoryCacheStorage) invalidate(path string, recursive bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	if elem, ok := s.entries[path]; ok {
+		s.remove(elem)
+	}
+	if !recursive {
+		return
+	}
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for key, elem := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(elem)
+		}
+	}
+}
+
+func (s *MemoryCacheStorage) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*memoryCacheEntry)
+	delete(s.entries, entry.path)
+	s.size -= int64(len(entry.data))
+}
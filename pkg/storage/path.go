@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidPath 路径不合法
+var ErrInvalidPath = errors.New("invalid path")
+
+// maxSegmentLength 单个路径段的最大长度
+const maxSegmentLength = 255
+
+// reservedNames Windows 保留的设备名，带扩展名时同样保留
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// CleanPath 规范化存储路径，返回以 / 开头的路径，原路径以 / 结尾时保留结尾的 /
+//
+// 空段和 . 段被去除；.. 段、反斜杠、NUL 和控制字符直接拒绝而不是尝试解析，
+// 保证任何路径与存储根目录拼接后都不会超出根目录
+func CleanPath(p string) (string, error) {
+	if !utf8.ValidString(p) {
+		return "", fmt.Errorf("%w: not valid UTF-8", ErrInvalidPath)
+	}
+
+	segments := make([]string, 0, strings.Count(p, "/")+1)
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("%w: parent directory reference", ErrInvalidPath)
+		}
+
+		for _, r := range segment {
+			if r == '\\' || r < 0x20 || r == 0x7f {
+				return "", fmt.Errorf("%w: illegal character %q", ErrInvalidPath, r)
+			}
+		}
+		segments = append(segments, segment)
+	}
+
+	cleaned := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && strings.HasSuffix(p, "/") {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
+
+// NormalizeRequestPath 规范化客户端请求的仓库路径，并校验是否符合 Maven 仓库布局
+//
+// 在 CleanPath 的基础上，路径段不能以 . 开头（保留给存储层的临时文件和属性文件），
+// 不能包含百分号（说明经过了多次编码）、Windows 保留字符和设备名，长度不能超过 255 字节
+func NormalizeRequestPath(p string) (string, error) {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return "", err
+	}
+
+	for _, segment := range strings.Split(strings.Trim(cleaned, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if err := validateSegment(segment); err != nil {
+			return "", err
+		}
+	}
+	return cleaned, nil
+}
+
+// validateSegment 校验单个 Maven 路径段
+func validateSegment(segment string) error {
+	if len(segment) > maxSegmentLength {
+		return fmt.Errorf("%w: segment longer than %d bytes", ErrInvalidPath, maxSegmentLength)
+	}
+	if strings.HasPrefix(segment, ".") {
+		return fmt.Errorf("%w: hidden segment %q", ErrInvalidPath, segment)
+	}
+	if strings.HasSuffix(segment, " ") {
+		return fmt.Errorf("%w: trailing space in segment %q", ErrInvalidPath, segment)
+	}
+	if i := strings.IndexAny(segment, `%:*?"<>|`); i >= 0 {
+		return fmt.Errorf("%w: illegal character %q", ErrInvalidPath, segment[i])
+	}
+	name, _, _ := strings.Cut(segment, ".")
+	if reservedNames[strings.ToUpper(name)] {
+		return fmt.Errorf("%w: reserved name %q", ErrInvalidPath, segment)
+	}
+	return nil
+}
+
+// resolvePath 将规范化后的路径拼接到本地根目录，路径不合法时返回错误
+func resolvePath(root string, p string) (string, error) {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(cleaned)), nil
+}
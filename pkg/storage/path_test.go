@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHostilePaths(t *testing.T) {
+	long := strings.Repeat("a", maxSegmentLength+1)
+
+	tests := []struct {
+		name string
+		path string
+		// clean 和 normalize 为期望的结果，为空表示应被拒绝
+		clean     string
+		normalize string
+	}{
+		{"plain", "/com/example/lib/1.0/lib-1.0.jar", "/com/example/lib/1.0/lib-1.0.jar", "/com/example/lib/1.0/lib-1.0.jar"},
+		{"directory", "/com/example/", "/com/example/", "/com/example/"},
+		{"root", "/", "/", "/"},
+		{"empty", "", "/", "/"},
+		{"duplicate slashes", "//com///example//lib", "/com/example/lib", "/com/example/lib"},
+		{"dot segments", "/./com/./example/.", "/com/example", "/com/example"},
+
+		// 上级目录
+		{"parent", "/..", "", ""},
+		{"parent prefix", "../etc/passwd", "", ""},
+		{"parent in middle", "/com/../../etc/passwd", "", ""},
+		{"parent at end", "/com/example/..", "", ""},
+		{"parent trailing slash", "/com/../", "", ""},
+		{"parent after slashes", "//..//etc", "", ""},
+
+		// 编码的分隔符和上级目录，请求路径在这里已经解码过一次，残留的百分号说明经过了多次编码
+		{"encoded parent", "/com/%2e%2e/etc", "/com/%2e%2e/etc", ""},
+		{"encoded parent upper", "/%2E%2E/%2E%2E/etc/passwd", "/%2E%2E/%2E%2E/etc/passwd", ""},
+		{"encoded slash", "/com%2f..%2fetc", "/com%2f..%2fetc", ""},
+		{"encoded backslash", "/com%5c..%5cetc", "/com%5c..%5cetc", ""},
+		{"double encoded", "/%252e%252e/etc", "/%252e%252e/etc", ""},
+
+		// 反斜杠
+		{"backslash parent", `/com\..\..\etc`, "", ""},
+		{"backslash separator", `/com\example`, "", ""},
+		{"leading backslash", `\etc\passwd`, "", ""},
+
+		// 绝对路径和盘符，绝对路径被视为仓库内的路径
+		{"absolute", "/etc/passwd", "/etc/passwd", "/etc/passwd"},
+		{"drive letter", "/C:/Windows/system32", "/C:/Windows/system32", ""},
+		{"drive letter only", "C:", "/C:", ""},
+		{"drive letter backslash", `C:\Windows`, "", ""},
+		{"unc", `\\server\share`, "", ""},
+		{"alternate data stream", "/lib-1.0.jar::$DATA", "/lib-1.0.jar::$DATA", ""},
+
+		// NUL 和控制字符
+		{"nul", "/com/lib\x00.jar", "", ""},
+		{"nul segment", "/\x00/etc", "", ""},
+		{"newline", "/com/lib\n.jar", "", ""},
+		{"carriage return", "/com/lib\r.jar", "", ""},
+		{"tab", "/com/\tlib", "", ""},
+		{"escape", "/com/\x1b[31m", "", ""},
+		{"delete", "/com/lib\x7f", "", ""},
+		{"invalid utf8", "/com/\xff\xfe", "", ""},
+
+		// 以 . 开头的段保留给存储层的临时文件、锁文件和属性文件
+		{"hidden", "/com/.hidden", "/com/.hidden", ""},
+		{"attributes", "/com/.lib-1.0.jar.attributes", "/com/.lib-1.0.jar.attributes", ""},
+		{"lock file", "/com/.lib-1.0.jar.lock", "/com/.lib-1.0.jar.lock", ""},
+		{"triple dot", "/com/...", "/com/...", ""},
+		{"git", "/.git/config", "/.git/config", ""},
+
+		// Windows 保留字符和设备名
+		{"reserved con", "/com/CON", "/com/CON", ""},
+		{"reserved nul lower", "/com/nul", "/com/nul", ""},
+		{"reserved with extension", "/com/aux.jar", "/com/aux.jar", ""},
+		{"reserved com port", "/com/COM1/lib.jar", "/com/COM1/lib.jar", ""},
+		{"reserved lpt port", "/lpt9.txt", "/lpt9.txt", ""},
+		{"not reserved", "/com/console/conf.jar", "/com/console/conf.jar", "/com/console/conf.jar"},
+		{"not reserved com10", "/com/COM10", "/com/COM10", "/com/COM10"},
+		{"wildcard", "/com/*.jar", "/com/*.jar", ""},
+		{"question mark", "/com/lib?.jar", "/com/lib?.jar", ""},
+		{"quote", `/com/"lib".jar`, `/com/"lib".jar`, ""},
+		{"angle brackets", "/com/<lib>.jar", "/com/<lib>.jar", ""},
+		{"pipe", "/com/lib|rm", "/com/lib|rm", ""},
+		{"trailing space", "/com/lib /x", "/com/lib /x", ""},
+
+		// 段长度
+		{"segment at limit", "/" + long[:maxSegmentLength], "/" + long[:maxSegmentLength], "/" + long[:maxSegmentLength]},
+		{"segment over limit", "/com/" + long, "/com/" + long, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkPath(t, "CleanPath", CleanPath, tt.path, tt.clean)
+			checkPath(t, "NormalizeRequestPath", NormalizeRequestPath, tt.path, tt.normalize)
+		})
+	}
+}
+
+func checkPath(t *testing.T, name string, fn func(string) (string, error), path, want string) {
+	t.Helper()
+	got, err := fn(path)
+	if want == "" {
+		if err == nil {
+			t.Errorf("%s(%q) = %q, want error", name, path, got)
+		} else if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%s(%q) error %v does not wrap ErrInvalidPath", name, path, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s(%q) unexpected error: %v", name, path, err)
+	} else if got != want {
+		t.Errorf("%s(%q) = %q, want %q", name, path, got, want)
+	}
+}
+
+func TestResolvePathStaysInRoot(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{"/..", "/a/../../b", `..\..\b`, "/a/\x00", "//../etc"} {
+		if resolved, err := resolvePath(root, p); err == nil {
+			t.Errorf("resolvePath(%q) = %q, want error", p, resolved)
+		}
+	}
+	for _, p := range []string{"/etc/passwd", "C:", "//a//b"} {
+		resolved, err := resolvePath(root, p)
+		if err != nil {
+			t.Errorf("resolvePath(%q) unexpected error: %v", p, err)
+			continue
+		}
+		if !strings.HasPrefix(resolved, root+"/") {
+			t.Errorf("resolvePath(%q) = %q escapes root %q", p, resolved, root)
+		}
+	}
+}
@@ -1,9 +1,8 @@
-// pkg/storage/prefixed.go
 package storage
 
 import (
-	"net/http"
-	"path/filepath"
+	"io"
+	"path"
 )
 
 // PrefixedStorage 为存储添加路径前缀
@@ -20,22 +19,73 @@ func NewPrefixedStorage(base Storage, prefix string) *PrefixedStorage {
 	}
 }
 
-func (s *PrefixedStorage) Read(path string) ([]byte, int, http.Header, error) {
-	fullPath := filepath.Join(s.prefix, path)
+func (s *PrefixedStorage) Read(path string) (*Content, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
 	return s.base.Read(fullPath)
 }
 
-func (s *PrefixedStorage) Write(path string, data []byte) error {
-	fullPath := filepath.Join(s.prefix, path)
-	return s.base.Write(fullPath, data)
+func (s *PrefixedStorage) Write(path string, r io.Reader, size int64) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	return s.base.Write(fullPath, r, size)
 }
 
 func (s *PrefixedStorage) List(path string) ([]FileInfo, error) {
-	fullPath := filepath.Join(s.prefix, path)
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
 	return s.base.List(fullPath)
 }
 
 func (s *PrefixedStorage) Exists(path string) bool {
-	fullPath := filepath.Join(s.prefix, path)
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return false
+	}
 	return s.base.Exists(fullPath)
 }
+
+func (s *PrefixedStorage) Delete(path string) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	return s.base.Delete(fullPath)
+}
+
+func (s *PrefixedStorage) Stat(path string) (*FileInfo, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.base.Stat(fullPath)
+}
+
+func (s *PrefixedStorage) LockPath(path string) (func(), error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return LockPath(s.base, fullPath)
+}
+
+func (s *PrefixedStorage) Touch(path string) {
+	if fullPath, err := s.resolve(path); err == nil {
+		Touch(s.base, fullPath)
+	}
+}
+
+// resolve 拼接前缀，先规范化路径，避免 .. 跳出前缀访问其他仓库
+func (s *PrefixedStorage) resolve(p string) (string, error) {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(s.prefix, cleaned), nil
+}
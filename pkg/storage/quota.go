@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// QuotaStorage 限制仓库占用的字节数和文件数的存储包装器
+// 使用量在创建时统计一次，之后随写入和删除增量更新
+type QuotaStorage struct {
+	base      Storage
+	name      string
+	maxBytes  int64 // 0 表示不限制
+	maxFiles  int64 // 0 表示不限制
+	softLimit int   // 软限制百分比，超过时写告警日志
+
+	locks *pathLocker // 同一路径的写入和删除串行执行，判断是否为新文件与更新使用量之间不会被打断
+
+	mu            sync.Mutex
+	bytes         int64
+	files         int64
+	reserved      int64 // 写入中的文件预占的字节数
+	reservedFiles int64 // 写入中的新文件预占的文件数
+	warned        bool
+}
+
+// QuotaUsage 配额使用情况
+type QuotaUsage struct {
+	Bytes    int64 `json:"bytes"`
+	Files    int64 `json:"files"`
+	MaxBytes int64 `json:"maxBytes"`
+	MaxFiles int64 `json:"maxFiles"`
+}
+
+// NewQuotaStorage 创建配额包装器并统计当前使用量
+func NewQuotaStorage(base Storage, name string, maxBytes, maxFiles int64, softLimit int) (*QuotaStorage, error) {
+	s := &QuotaStorage{
+		base:      base,
+		name:      name,
+		maxBytes:  maxBytes,
+		maxFiles:  maxFiles,
+		softLimit: softLimit,
+		locks:     newPathLocker(),
+	}
+
+	err := Walk(base, "/", func(p string, info FileInfo) error {
+		s.bytes += info.Size
+		s.files++
+		return nil
+	})
+	if err != nil && base.Exists("/") {
+		return nil, fmt.Errorf("calculate usage of %s failed: %w", name, err)
+	}
+
+	s.checkSoftLimit()
+	return s, nil
+}
+
+func (s *QuotaStorage) Read(path string) (*Content, error) {
+	return s.base.Read(path)
+}
+
+func (s *QuotaStorage) Write(p string, r io.Reader, size int64) error {
+	unlock := s.locks.Lock(tierKey(p))
+	defer unlock()
+
+	oldSize, replaced := s.fileSize(p)
+
+	s.mu.Lock()
+	// 新文件与字节数一样先预占，并发写入不会超出文件数限制
+	reserveFiles := int64(0)
+	if !replaced {
+		if s.maxFiles > 0 && s.files+s.reservedFiles+1 > s.maxFiles {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %s has reached %d files", ErrQuotaExceeded, s.name, s.maxFiles)
+		}
+		reserveFiles = 1
+	}
+
+	// 长度已知时预占空间，长度未知时写入过程中按当前剩余空间限制
+	reserve := int64(0)
+	if s.maxBytes > 0 {
+		available := s.maxBytes - s.bytes - s.reserved + oldSize
+		if size >= 0 {
+			if size > available {
+				s.mu.Unlock()
+				return fmt.Errorf("%w: %s has %d bytes available, need %d", ErrQuotaExceeded, s.name, available, size)
+			}
+			reserve = size
+		} else {
+			r = &quotaReader{r: r, remaining: available, name: s.name}
+		}
+	}
+	s.reserved += reserve
+	s.reservedFiles += reserveFiles
+	s.mu.Unlock()
+
+	counter := &countingReader{r: r}
+	err := s.base.Write(p, counter, size)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reserved -= reserve
+	s.reservedFiles -= reserveFiles
+	if err != nil {
+		return err
+	}
+
+	s.bytes += counter.n - oldSize
+	if !replaced {
+		s.files++
+	}
+	s.checkSoftLimit()
+	return nil
+}
+
+func (s *QuotaStorage) List(path string) ([]FileInfo, error) {
+	return s.base.List(path)
+}
+
+func (s *QuotaStorage) Exists(path string) bool {
+	return s.base.Exists(path)
+}
+
+func (s *QuotaStorage) Stat(path string) (*FileInfo, error) {
+	return s.base.Stat(path)
+}
+
+func (s *QuotaStorage) Delete(p string) error {
+	unlock := s.locks.Lock(tierKey(p))
+	defer unlock()
+
+	// 删除前统计释放的空间
+	var freedBytes, freedFiles int64
+	if size, ok := s.fileSize(p); ok {
+		freedBytes, freedFiles = size, 1
+	} else {
+		Walk(s.base, p, func(_ string, info FileInfo) error {
+			freedBytes += info.Size
+			freedFiles++
+			return nil
+		})
+	}
+
+	if err := s.base.Delete(p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bytes -= freedBytes
+	s.files -= freedFiles
+	s.checkSoftLimit()
+	return nil
+}
+
+func (s *QuotaStorage) Touch(path string) {
+	Touch(s.base, path)
+}
+
+func (s *QuotaStorage) LockPath(path string) (func(), error) {
+	return LockPath(s.base, path)
+}
+
+// Usage 返回当前使用量
+func (s *QuotaStorage) Usage() QuotaUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return QuotaUsage{
+		Bytes:    s.bytes,
+		Files:    s.files,
+		MaxBytes: s.maxBytes,
+		MaxFiles: s.maxFiles,
+	}
+}
+
+// fileSize 返回已存在文件的大小
+func (s *QuotaStorage) fileSize(p string) (int64, bool) {
+	info, err := s.base.Stat(p)
+	if err != nil || info.IsDir {
+		return 0, false
+	}
+	return info.Size, true
+}
+
+// checkSoftLimit 使用量越过软限制时告警一次，回落后重置，调用方需持有锁
+func (s *QuotaStorage) checkSoftLimit() {
+	if s.softLimit <= 0 {
+		return
+	}
+
+	over := false
+	if s.maxBytes > 0 && s.bytes*100 >= s.maxBytes*int64(s.softLimit) {
+		over = true
+	}
+	if s.maxFiles > 0 && s.files*100 >= s.maxFiles*int64(s.softLimit) {
+		over = true
+	}
+
+	if over && !s.warned {
+		log.Warnf("repository %s storage usage above %d%% of quota: %d/%d bytes, %d/%d files",
+			s.name, s.softLimit, s.bytes, s.maxBytes, s.files, s.maxFiles)
+	}
+	s.warned = over
+}
+
+// countingReader 统计实际读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// quotaReader 读取超过剩余配额时返回 ErrQuotaExceeded
+type quotaReader struct {
+	r         io.Reader
+	remaining int64
+	name      string
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, fmt.Errorf("%w: %s has no space left", ErrQuotaExceeded, r.name)
+	}
+	return n, err
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// gatedStorage 写入前等待放行，让多个写入同时通过配额检查
+type gatedStorage struct {
+	Storage
+	gate chan struct{}
+}
+
+func (s *gatedStorage) Write(path string, r io.Reader, size int64) error {
+	<-s.gate
+	return s.Storage.Write(path, r, size)
+}
+
+func TestQuotaStorageConcurrentNewFiles(t *testing.T) {
+	base := &gatedStorage{Storage: NewFileSystemStorage(t.TempDir()), gate: make(chan struct{})}
+	quota, err := NewQuotaStorage(base, "test", 0, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 5
+	errs := make(chan error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- quota.Write(fmt.Sprintf("/f%d", i), bytes.NewReader([]byte("x")), 1)
+		}(i)
+	}
+	// 超出限制的写入在预占时立即失败，只有两个写入在等待放行
+	for i := 0; i < writers-2; i++ {
+		if err := <-errs; !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("write %d = %v, want ErrQuotaExceeded", i, err)
+		}
+	}
+	close(base.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("reserved write failed: %v", err)
+		}
+	}
+
+	if usage := quota.Usage(); usage.Files != 2 {
+		t.Errorf("files = %d, want 2", usage.Files)
+	}
+	// 覆盖已有文件不占用新的文件数
+	entries, err := quota.List("/")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("List = %v, %v", entries, err)
+	}
+	if err := WriteBytes(quota, "/"+entries[0].Name, []byte("y")); err != nil {
+		t.Errorf("overwrite = %v", err)
+	}
+}
+
+func TestQuotaStorageConcurrentSamePath(t *testing.T) {
+	base := &gatedStorage{Storage: NewFileSystemStorage(t.TempDir()), gate: make(chan struct{})}
+	quota, err := NewQuotaStorage(base, "test", 100, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := quota.Write("/com/ex/lib.jar", bytes.NewReader([]byte("jar")), 3); err != nil {
+				t.Errorf("Write: %v", err)
+			}
+		}()
+	}
+	close(base.gate)
+	wg.Wait()
+
+	// 同一个新文件的并发写入只统计一次
+	if usage := quota.Usage(); usage.Files != 1 || usage.Bytes != 3 {
+		t.Errorf("usage = %+v, want 1 file 3 bytes", usage)
+	}
+}
@@ -0,0 +1,470 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// S3Options S3 兼容对象存储的连接参数
+type S3Options struct {
+	Endpoint  string       // 服务地址，例如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string       // 签名使用的区域
+	Bucket    string       // 存储桶名称
+	Prefix    string       // 对象键前缀
+	AccessKey string       // 访问密钥
+	SecretKey string       // 私有密钥
+	PathStyle bool         // 使用 endpoint/bucket/key 形式访问，而不是 bucket.endpoint/key
+	Client    *http.Client // 可选，默认使用带连接和响应头超时的客户端
+}
+
+// S3Storage 基于 S3 协议的对象存储，适用于多个无状态实例共享数据
+type S3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	pathStyle bool
+	client    *http.Client
+	signer    *s3Signer
+}
+
+// NewS3Storage 创建 S3 存储
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if opts.Endpoint == "" {
+		return nil, errors.New("s3 endpoint is required")
+	}
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint failed: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", opts.Endpoint)
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = newS3Client()
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		bucket:    opts.Bucket,
+		prefix:    strings.Trim(opts.Prefix, "/"),
+		pathStyle: opts.PathStyle,
+		client:    client,
+		signer: &s3Signer{
+			accessKey: opts.AccessKey,
+			secretKey: opts.SecretKey,
+			region:    region,
+		},
+	}, nil
+}
+
+// newS3Client 创建访问对象存储的默认客户端，只限制建立连接、等待响应头和空闲连接的时间，
+// 不限制大文件的传输时间
+func newS3Client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+func (s *S3Storage) Read(p string) (*Content, error) {
+	resp, err := s.do(http.MethodGet, s.key(p), nil, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s.responseError(resp, p)
+	}
+
+	content := &Content{
+		ReadCloser:  resp.Body,
+		Size:        resp.ContentLength,
+		ContentType: getContentType(p),
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		content.ModTime = t
+	}
+	return content, nil
+}
+
+func (s *S3Storage) Write(p string, r io.Reader, size int64) error {
+	// PUT 需要明确的 Content-Length，长度未知时先缓冲到临时文件
+	if size < 0 {
+		tmp, err := os.CreateTemp("", "maven-proxy-s3-*")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}()
+
+		if size, err = io.Copy(tmp, r); err != nil {
+			return err
+		}
+		if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r = tmp
+	}
+
+	digest := newDigestReader(r)
+	if err := s.putObject(s.key(p), digest, size); err != nil {
+		return err
+	}
+
+	// 属性对象只是加速手段，写入失败时 Stat 退化为不带摘要
+	info, err := s.head(s.key(p))
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(digest.attributes(p, info.ModTime)); err == nil {
+			err = s.putObject(s.attributesKey(p), bytes.NewReader(data), int64(len(data)))
+		}
+	}
+	if err != nil {
+		log.Warnf("write attributes of %s failed: %v", p, err)
+	}
+	return nil
+}
+
+func (s *S3Storage) putObject(key string, r io.Reader, size int64) error {
+	header := http.Header{"Content-Type": []string{getContentType(key)}}
+	resp, err := s.do(http.MethodPut, key, nil, header, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp, key)
+	}
+	return nil
+}
+
+func (s *S3Storage) List(p string) ([]FileInfo, error) {
+	prefix := s.key(p)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	fileInfos := []FileInfo{}
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": []string{"2"},
+			"prefix":    []string{prefix},
+			"delimiter": []string{"/"},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		result, err := s.listObjects(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cp := range result.CommonPrefixes {
+			name := path.Base(strings.TrimSuffix(cp.Prefix, "/"))
+			fileInfos = append(fileInfos, FileInfo{Name: name, IsDir: true})
+		}
+		for _, obj := range result.Contents {
+			if obj.Key == prefix || isHiddenFile(path.Base(obj.Key)) {
+				continue
+			}
+			fileInfos = append(fileInfos, FileInfo{
+				Name:    path.Base(obj.Key),
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	// 对象存储没有真正的目录，空前缀视为目录不存在
+	if len(fileInfos) == 0 && prefix != "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return fileInfos, nil
+}
+
+func (s *S3Storage) Exists(p string) bool {
+	key := s.key(p)
+	if key != "" {
+		if resp, err := s.do(http.MethodHead, key, nil, nil, nil, -1); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return true
+			}
+		}
+		key += "/"
+	}
+
+	// 对象不存在时按目录前缀检查
+	result, err := s.listObjects(url.Values{
+		"list-type": []string{"2"},
+		"prefix":    []string{key},
+		"max-keys":  []string{"1"},
+	})
+	return err == nil && len(result.Contents) > 0
+}
+
+func (s *S3Storage) Stat(p string) (*FileInfo, error) {
+	key := s.key(p)
+	info, err := s.head(key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		// 对象不存在时按目录前缀检查
+		if !s.Exists(p) {
+			return nil, err
+		}
+		return &FileInfo{Name: path.Base(key), IsDir: true}, nil
+	}
+
+	info.ContentType = getContentType(p)
+	if resp, err := s.do(http.MethodGet, s.attributesKey(p), nil, nil, nil, -1); err == nil {
+		if resp.StatusCode == http.StatusOK {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			if attrs, err := parseAttributes(data, info.Size, info.ModTime); err == nil {
+				info.SHA1 = attrs.SHA1
+				info.SHA256 = attrs.SHA256
+			}
+		}
+		resp.Body.Close()
+	}
+	return info, nil
+}
+
+func (s *S3Storage) Delete(p string) error {
+	key := s.key(p)
+	if key == s.prefix {
+		return fmt.Errorf("refuse to delete storage root")
+	}
+
+	// 先按单个对象删除
+	if _, err := s.head(key); err == nil {
+		if err := s.deleteObject(key); err != nil {
+			return err
+		}
+		if err := s.deleteObject(s.attributesKey(p)); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Warnf("delete attributes of %s failed: %v", p, err)
+		}
+		return nil
+	}
+
+	// 按目录前缀删除所有对象
+	deleted := 0
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": []string{"2"},
+			"prefix":    []string{key + "/"},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		result, err := s.listObjects(query)
+		if err != nil {
+			return err
+		}
+		for _, obj := range result.Contents {
+			if err := s.deleteObject(obj.Key); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return nil
+}
+
+func (s *S3Storage) deleteObject(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, nil, nil, -1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s.responseError(resp, key)
+	}
+	return nil
+}
+
+// s3ListResult ListObjectsV2 响应
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+func (s *S3Storage) listObjects(query url.Values) (*s3ListResult, error) {
+	resp, err := s.do(http.MethodGet, "", query, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError(resp, query.Get("prefix"))
+	}
+
+	result := &s3ListResult{}
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("decode s3 list response failed: %w", err)
+	}
+	return result, nil
+}
+
+// head 获取单个对象的元信息
+func (s *S3Storage) head(key string) (*FileInfo, error) {
+	resp, err := s.do(http.MethodHead, key, nil, nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError(resp, key)
+	}
+
+	info := &FileInfo{
+		Name: path.Base(key),
+		Size: resp.ContentLength,
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+	return info, nil
+}
+
+// attributesKey 返回文件对应的属性对象键
+func (s *S3Storage) attributesKey(p string) string {
+	key := s.key(p)
+	return path.Join(path.Dir(key), attributesName(path.Base(key)))
+}
+
+// key 将存储路径转换为对象键
+func (s *S3Storage) key(p string) string {
+	key := strings.Trim(path.Clean("/"+p), "/")
+	if s.prefix == "" {
+		return key
+	}
+	if key == "" {
+		return s.prefix
+	}
+	return s.prefix + "/" + key
+}
+
+// do 发送签名请求，body 为 nil 时 size 被忽略
+func (s *S3Storage) do(method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	host := s.endpoint.Host
+	uriPath := strings.TrimSuffix(s.endpoint.Path, "/")
+	if s.pathStyle {
+		uriPath += "/" + s.bucket
+	} else {
+		host = s.bucket + "." + host
+	}
+	uriPath += "/" + key
+	canonicalURI := s3Escape(uriPath, false)
+
+	u := &url.URL{
+		Scheme: s.endpoint.Scheme,
+		Host:   host,
+		Opaque: "//" + host + canonicalURI,
+	}
+	if len(query) > 0 {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Body = io.NopCloser(body)
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+
+	s.signer.sign(req, canonicalURI, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s failed: %w", method, key, err)
+	}
+	return resp, nil
+}
+
+// responseError 将 S3 错误响应转换为 error
+func (s *S3Storage) responseError(resp *http.Response, p string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+		return fmt.Errorf("s3 request for %s failed: %d %s: %s", p, resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+	return fmt.Errorf("s3 request for %s failed: %d", p, resp.StatusCode)
+}
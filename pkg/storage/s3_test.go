@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestS3Storage 连接到进程内的 S3 替身，virtual-host 寻址的请求同样被拨号到替身
+func newTestS3Storage(t *testing.T, pathStyle bool, prefix string) (*S3Storage, *fakeS3) {
+	t.Helper()
+	fake, srv := newFakeS3("maven")
+	t.Cleanup(srv.Close)
+
+	addr := srv.Listener.Addr().String()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	s, err := NewS3Storage(S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "maven",
+		Prefix:    prefix,
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: pathStyle,
+		Client:    &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestS3Storage(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		for _, prefix := range []string{"", "/mirror/central/"} {
+			name := fmt.Sprintf("pathStyle=%v/prefix=%q", pathStyle, prefix)
+			t.Run(name, func(t *testing.T) {
+				testS3Storage(t, pathStyle, prefix)
+			})
+		}
+	}
+}
+
+func testS3Storage(t *testing.T, pathStyle bool, prefix string) {
+	s, fake := newTestS3Storage(t, pathStyle, prefix)
+	fake.pageSize = 2 // 强制 List 和 Delete 走分页
+
+	jar := []byte("jar content")
+	if err := s.Write("/com/ex/lib/1.0/lib-1.0.jar", bytes.NewReader(jar), int64(len(jar))); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// 长度未知时先缓冲再上传
+	pom := []byte("<project/>")
+	if err := s.Write("/com/ex/lib/1.0/lib-1.0.pom", bytes.NewReader(pom), -1); err != nil {
+		t.Fatalf("Write unknown size: %v", err)
+	}
+	for _, name := range []string{"lib-1.0.jar.sha1", "lib-1.0.jar.md5", "lib-1.0.pom.sha1"} {
+		if err := WriteBytes(s, "/com/ex/lib/1.0/"+name, []byte(name)); err != nil {
+			t.Fatalf("Write %s: %v", name, err)
+		}
+	}
+	if err := WriteBytes(s, "/com/ex/lib/2.0/lib-2.0.jar", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 所有对象都在前缀之下，寻址方式与配置一致
+	wantPrefix := strings.Trim(prefix, "/")
+	for _, key := range fake.keys() {
+		if wantPrefix != "" && !strings.HasPrefix(key, wantPrefix+"/") {
+			t.Errorf("object %q outside prefix %q", key, wantPrefix)
+		}
+	}
+	fake.mu.Lock()
+	for _, req := range fake.requests {
+		if req.virtualHost == pathStyle {
+			t.Errorf("%s %s used virtualHost=%v with pathStyle=%v", req.method, req.key, req.virtualHost, pathStyle)
+		}
+	}
+	fake.mu.Unlock()
+
+	// Read
+	content, err := s.Read("/com/ex/lib/1.0/lib-1.0.jar")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if !bytes.Equal(data, jar) || content.Size != int64(len(jar)) || content.ModTime.IsZero() {
+		t.Errorf("Read = %q size %d modTime %v", data, content.Size, content.ModTime)
+	}
+	if _, err := s.Read("/com/ex/lib/1.0/missing.jar"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Read missing: %v, want ErrNotFound", err)
+	}
+
+	// Stat 返回写入时记录的摘要，目录按前缀判断
+	info, err := s.Stat("/com/ex/lib/1.0/lib-1.0.jar")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	sum := sha1.Sum(jar)
+	if info.IsDir || info.Size != int64(len(jar)) || info.SHA1 != hex.EncodeToString(sum[:]) || info.SHA256 == "" {
+		t.Errorf("Stat = %+v", info)
+	}
+	if info, err := s.Stat("/com/ex/lib"); err != nil || !info.IsDir {
+		t.Errorf("Stat dir = %+v, %v", info, err)
+	}
+	if _, err := s.Stat("/com/ex/nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat missing: %v, want ErrNotFound", err)
+	}
+
+	// Exists
+	for p, want := range map[string]bool{
+		"/com/ex/lib/1.0/lib-1.0.pom": true,
+		"/com/ex/lib/1.0":             true,
+		"/com":                        true,
+		"/com/ex/lib/1.0/lib-1.0.war": false,
+		"/com/ex/li":                  false,
+		"/org":                        false,
+	} {
+		if got := s.Exists(p); got != want {
+			t.Errorf("Exists(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	// List 合并多页结果，不返回属性对象
+	entries, err := s.List("/com/ex/lib/1.0")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	got := listNames(entries)
+	want := "lib-1.0.jar lib-1.0.jar.md5 lib-1.0.jar.sha1 lib-1.0.pom lib-1.0.pom.sha1"
+	if got != want {
+		t.Errorf("List = %q, want %q", got, want)
+	}
+	entries, err = s.List("/com/ex/lib/")
+	if err != nil {
+		t.Fatalf("List dir: %v", err)
+	}
+	if got := listNames(entries); got != "1.0/ 2.0/" {
+		t.Errorf("List dir = %q", got)
+	}
+	root, err := s.List("/")
+	if err != nil || listNames(root) != "com/" {
+		t.Errorf("List root = %q, %v", listNames(root), err)
+	}
+	if _, err := s.List("/com/ex/nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("List missing: %v, want ErrNotFound", err)
+	}
+
+	// Delete 单个对象时同时删除属性对象
+	if err := s.Delete("/com/ex/lib/1.0/lib-1.0.jar"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if s.Exists("/com/ex/lib/1.0/lib-1.0.jar") {
+		t.Error("file exists after Delete")
+	}
+	for _, key := range fake.keys() {
+		if strings.HasSuffix(key, ".lib-1.0.jar"+attributesSuffix) {
+			t.Errorf("attributes object %q left after Delete", key)
+		}
+	}
+	if err := s.Delete("/com/ex/lib/1.0/lib-1.0.jar"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete missing: %v, want ErrNotFound", err)
+	}
+
+	// 按目录删除跨越多页的所有对象
+	if err := s.Delete("/com/ex/lib/1.0"); err != nil {
+		t.Fatalf("Delete dir: %v", err)
+	}
+	if s.Exists("/com/ex/lib/1.0") || !s.Exists("/com/ex/lib/2.0/lib-2.0.jar") {
+		t.Errorf("after Delete dir: keys %v", fake.keys())
+	}
+	if err := s.Delete("/"); err == nil {
+		t.Error("Delete root succeeded")
+	}
+}
+
+func listNames(entries []FileInfo) string {
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir {
+			names = append(names, e.Name+"/")
+		} else {
+			names = append(names, e.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func TestS3StorageDefaultClientTimeouts(t *testing.T) {
+	s, err := NewS3Storage(S3Options{Endpoint: "http://127.0.0.1:9000", Bucket: "maven"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := s.client.Transport.(*http.Transport)
+	if !ok || s.client == http.DefaultClient {
+		t.Fatalf("default client uses transport %T", s.client.Transport)
+	}
+	if transport.DialContext == nil || transport.ResponseHeaderTimeout == 0 || transport.IdleConnTimeout == 0 || transport.TLSHandshakeTimeout == 0 {
+		t.Errorf("default transport missing timeouts: %+v", transport)
+	}
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeS3 进程内的 S3 替身，支持对象的 PUT、GET、HEAD、DELETE 和带分隔符、分页的 ListObjectsV2，
+// 同时接受 path-style 和 virtual-host 两种寻址方式
+type fakeS3 struct {
+	bucket   string
+	pageSize int // 每页返回的最大条目数，较小的值用于测试分页
+
+	mu       sync.Mutex
+	objects  map[string]fakeObject
+	requests []fakeRequest
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// fakeRequest 记录请求使用的寻址方式和对象键
+type fakeRequest struct {
+	method      string
+	key         string
+	virtualHost bool
+}
+
+func newFakeS3(bucket string) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		bucket:   bucket,
+		pageSize: 1000,
+		objects:  make(map[string]fakeObject),
+	}
+	return f, httptest.NewServer(f)
+}
+
+// keys 返回所有对象键
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") || r.Header.Get("X-Amz-Date") == "" {
+		f.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	// virtual-host 寻址时存储桶在主机名中，否则是路径的第一段
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	host, _, _ := strings.Cut(r.Host, ":")
+	virtualHost := strings.HasPrefix(host, f.bucket+".")
+	if !virtualHost {
+		bucket, rest, _ := strings.Cut(key, "/")
+		if bucket != f.bucket {
+			f.error(w, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		key = rest
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, fakeRequest{method: r.Method, key: key, virtualHost: virtualHost})
+	f.mu.Unlock()
+
+	if key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+		f.list(w, r)
+		return
+	}
+	if key == "" {
+		f.error(w, http.StatusBadRequest, "InvalidRequest")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.mu.Lock()
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now().UTC().Truncate(time.Second)}
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet, http.MethodHead:
+		f.mu.Lock()
+		obj, ok := f.objects[key]
+		f.mu.Unlock()
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+
+	case http.MethodDelete:
+		// 与 S3 一致，删除不存在的对象同样返回 204
+		f.mu.Lock()
+		delete(f.objects, key)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+type fakeListResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Prefix                string   `xml:"Prefix"`
+	KeyCount              int      `xml:"KeyCount"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+	Contents              []struct {
+		Key          string `xml:"Key"`
+		Size         int64  `xml:"Size"`
+		LastModified string `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// list 实现 ListObjectsV2，延续令牌为上一页最后一个条目
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	after := query.Get("continuation-token")
+	maxKeys := f.pageSize
+	if n, err := strconv.Atoi(query.Get("max-keys")); err == nil && n < maxKeys {
+		maxKeys = n
+	}
+
+	// 对象和公共前缀按字典序合并成一个列表后分页
+	type entry struct {
+		name     string
+		isPrefix bool
+		obj      fakeObject
+	}
+	f.mu.Lock()
+	seen := make(map[string]bool)
+	entries := []entry{}
+	for key, obj := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if !seen[common] {
+					seen[common] = true
+					entries = append(entries, entry{name: common, isPrefix: true})
+				}
+				continue
+			}
+		}
+		entries = append(entries, entry{name: key, obj: obj})
+	}
+	f.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	result := fakeListResult{Prefix: prefix}
+	for _, e := range entries {
+		if after != "" && e.name <= after {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		if e.isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, struct {
+				Prefix string `xml:"Prefix"`
+			}{e.name})
+		} else {
+			result.Contents = append(result.Contents, struct {
+				Key          string `xml:"Key"`
+				Size         int64  `xml:"Size"`
+				LastModified string `xml:"LastModified"`
+			}{e.name, int64(len(e.obj.data)), e.obj.modTime.Format(time.RFC3339)})
+		}
+		result.KeyCount++
+		result.NextContinuationToken = e.name
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, "<Error><Code>"+code+"</Code><Message>"+http.StatusText(status)+"</Message></Error>")
+}
@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardReplicas 每个卷在哈希环上的虚拟节点数
+const shardReplicas = 128
+
+// usageRefreshInterval 后台重新统计各卷文件数和大小的周期
+const usageRefreshInterval = 10 * time.Minute
+
+// ShardedStorage 将文件按路径的一致性哈希分布到多个目录（通常位于不同磁盘）的存储后端
+//
+// 卷以目录路径标识，新增卷后只有少量文件需要移动。移动由后台重新平衡完成，
+// 期间读取先查找文件所属的卷，找不到时再查找其他卷
+type ShardedStorage struct {
+	volumes []*shardVolume
+	ring    []shardNode
+	locks   *pathLocker
+
+	mu        sync.Mutex
+	balancing bool
+	lastRun   time.Time
+	moved     uint64
+	lastErr   string
+	usage     []volumeTotals // 最近一次统计的各卷使用量，遍历卷的开销较大，由后台定期刷新
+	usageTime time.Time
+
+	refreshing int32
+	stopChan   chan struct{}
+}
+
+// volumeTotals 遍历卷得到的统计值
+type volumeTotals struct {
+	files     int64
+	bytes     int64
+	misplaced int64
+}
+
+type shardVolume struct {
+	path    string
+	storage *FileSystemStorage
+}
+
+type shardNode struct {
+	hash   uint32
+	volume int
+}
+
+// VolumeUsage 单个卷的使用情况
+type VolumeUsage struct {
+	Path       string `json:"path"`
+	Files      int64  `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	Misplaced  int64  `json:"misplaced"` // 不属于该卷、等待重新平衡的文件数
+	DiskTotal  uint64 `json:"diskTotal"`
+	DiskFree   uint64 `json:"diskFree"`
+	DiskStatus string `json:"diskStatus,omitempty"` // 无法获取磁盘容量时的原因
+}
+
+// ShardStats 分片存储的状态
+type ShardStats struct {
+	Volumes   []VolumeUsage `json:"volumes"`
+	UsageTime time.Time     `json:"usageTime"` // 文件数和大小的统计时间，尚未统计时为零值
+	Balancing bool          `json:"balancing"`
+	LastRun   time.Time     `json:"lastRun"`
+	Moved     uint64        `json:"moved"`
+	LastError string        `json:"lastError,omitempty"`
+}
+
+// NewShardedStorage 创建分片存储，paths 为各个卷的根目录
+func NewShardedStorage(paths []string) (*ShardedStorage, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("sharded storage requires at least one volume")
+	}
+
+	s := &ShardedStorage{locks: newPathLocker(), stopChan: make(chan struct{})}
+	seen := make(map[string]bool)
+	for i, p := range paths {
+		p = filepath.Clean(p)
+		if seen[p] {
+			return nil, fmt.Errorf("duplicate volume: %s", p)
+		}
+		seen[p] = true
+
+		s.volumes = append(s.volumes, &shardVolume{path: p, storage: NewFileSystemStorage(p)})
+		for r := 0; r < shardReplicas; r++ {
+			s.ring = append(s.ring, shardNode{hash: shardHash(p + "#" + strconv.Itoa(r)), volume: i})
+		}
+	}
+	sort.Slice(s.ring, func(i, j int) bool {
+		return s.ring[i].hash < s.ring[j].hash
+	})
+	return s, nil
+}
+
+// EnableFileLocks 在所有卷上启用锁文件
+func (s *ShardedStorage) EnableFileLocks(opts LockOptions) {
+	for _, volume := range s.volumes {
+		volume.storage.EnableFileLocks(opts)
+	}
+}
+
+// CleanupTempFiles 删除所有卷上遗留的临时文件和锁文件
+func (s *ShardedStorage) CleanupTempFiles(olderThan time.Duration) (int, error) {
+	total := 0
+	for _, volume := range s.volumes {
+		removed, err := volume.storage.CleanupTempFiles(olderThan)
+		total += removed
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (s *ShardedStorage) Read(path string) (*Content, error) {
+	var content *Content
+	err := s.lookup(path, func(volume *shardVolume) error {
+		var err error
+		content, err = volume.storage.Read(path)
+		return err
+	})
+	return content, err
+}
+
+// Write 写入文件所属的卷，并删除重新平衡前留在其他卷上的旧副本
+func (s *ShardedStorage) Write(path string, r io.Reader, size int64) error {
+	key, err := shardKey(path)
+	if err != nil {
+		return err
+	}
+
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	owner := s.owner(key)
+	if err := s.volumes[owner].storage.Write(path, r, size); err != nil {
+		return err
+	}
+	s.removeCopies(path, owner)
+	return nil
+}
+
+// List 合并所有卷上同一目录的内容
+func (s *ShardedStorage) List(path string) ([]FileInfo, error) {
+	merged := make(map[string]FileInfo)
+	var firstErr error
+	found := false
+
+	for _, volume := range s.volumes {
+		entries, err := volume.storage.List(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		for _, entry := range entries {
+			if existing, ok := merged[entry.Name]; ok && (existing.IsDir || existing.ModTime.After(entry.ModTime)) {
+				continue
+			}
+			merged[entry.Name] = entry
+		}
+	}
+	if !found {
+		return nil, firstErr
+	}
+
+	fileInfos := make([]FileInfo, 0, len(merged))
+	for _, entry := range merged {
+		fileInfos = append(fileInfos, entry)
+	}
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].Name < fileInfos[j].Name
+	})
+	return fileInfos, nil
+}
+
+func (s *ShardedStorage) Exists(path string) bool {
+	for _, volume := range s.volumes {
+		if volume.storage.Exists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ShardedStorage) Stat(path string) (*FileInfo, error) {
+	var info *FileInfo
+	err := s.lookup(path, func(volume *shardVolume) error {
+		var err error
+		info, err = volume.storage.Stat(path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Delete 删除所有卷上的文件或目录
+func (s *ShardedStorage) Delete(path string) error {
+	key, err := shardKey(path)
+	if err != nil {
+		return err
+	}
+
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	deleted := false
+	for _, volume := range s.volumes {
+		err := volume.storage.Delete(path)
+		if err == nil {
+			deleted = true
+			continue
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	if !deleted {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return nil
+}
+
+// LockPath 在路径所属的卷上加锁，所有实例的卷配置相同，同一路径总是锁在同一个卷上
+func (s *ShardedStorage) LockPath(path string) (func(), error) {
+	key, err := shardKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.volumes[s.owner(key)].storage.LockPath(path)
+}
+
+// Start 在后台执行一次重新平衡，将新增卷之后位置不正确的文件移到所属的卷，
+// 重新平衡结束后统计各卷的使用情况，之后定期刷新
+func (s *ShardedStorage) Start() {
+	s.Trigger()
+
+	go func() {
+		ticker := time.NewTicker(usageRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.RefreshUsage()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定期统计
+func (s *ShardedStorage) Stop() {
+	close(s.stopChan)
+}
+
+// Trigger 在后台立即执行一次重新平衡，已在执行时返回 false
+func (s *ShardedStorage) Trigger() bool {
+	if !s.begin() {
+		return false
+	}
+	go func() {
+		moved, err := s.rebalance()
+		if err != nil {
+			log.Warnf("rebalance sharded storage failed: %v", err)
+		} else if moved > 0 {
+			log.Infof("rebalanced sharded storage, moved %d files", moved)
+		}
+	}()
+	return true
+}
+
+// Rebalance 执行一次重新平衡，返回移动的文件数
+func (s *ShardedStorage) Rebalance() (int, error) {
+	if !s.begin() {
+		return 0, fmt.Errorf("rebalance already running")
+	}
+	return s.rebalance()
+}
+
+func (s *ShardedStorage) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.balancing {
+		return false
+	}
+	s.balancing = true
+	return true
+}
+
+func (s *ShardedStorage) rebalance() (int, error) {
+	moved := 0
+	var err error
+	for i, volume := range s.volumes {
+		err = Walk(volume.storage, "/", func(p string, _ FileInfo) error {
+			if s.owner(shardKeyOf(p)) == i {
+				return nil
+			}
+			if err := s.move(p, i); err != nil {
+				log.Warnf("move %s from %s failed: %v", p, volume.path, err)
+				return nil
+			}
+			moved++
+			atomic.AddUint64(&s.moved, 1)
+			return nil
+		})
+		if err != nil && volume.storage.Exists("/") {
+			break
+		}
+		err = nil
+	}
+
+	s.mu.Lock()
+	s.balancing = false
+	s.lastRun = time.Now()
+	s.lastErr = ""
+	if err != nil {
+		s.lastErr = err.Error()
+	}
+	s.mu.Unlock()
+
+	s.RefreshUsage()
+	return moved, err
+}
+
+// move 将文件从 from 卷移到所属的卷，所属的卷已有该文件时说明已被重新写入，只删除旧副本
+func (s *ShardedStorage) move(p string, from int) error {
+	key := shardKeyOf(p)
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	source := s.volumes[from].storage
+	owner := s.volumes[s.owner(key)].storage
+	if !owner.Exists(p) {
+		content, err := source.Read(p)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		err = owner.Write(p, content, content.Size)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := source.Delete(p); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// RefreshUsage 遍历各卷重新统计文件数和大小，已在统计时直接返回
+func (s *ShardedStorage) RefreshUsage() {
+	if !atomic.CompareAndSwapInt32(&s.refreshing, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&s.refreshing, 0)
+
+	totals := make([]volumeTotals, len(s.volumes))
+	for i, volume := range s.volumes {
+		Walk(volume.storage, "/", func(p string, info FileInfo) error {
+			totals[i].files++
+			totals[i].bytes += info.Size
+			if s.owner(shardKeyOf(p)) != i {
+				totals[i].misplaced++
+			}
+			return nil
+		})
+	}
+
+	s.mu.Lock()
+	s.usage = totals
+	s.usageTime = time.Now()
+	s.mu.Unlock()
+}
+
+// Stats 返回最近一次统计的各卷使用量和当前的磁盘容量，不遍历卷
+func (s *ShardedStorage) Stats() ShardStats {
+	s.mu.Lock()
+	totals := s.usage
+	stats := ShardStats{
+		UsageTime: s.usageTime,
+		Balancing: s.balancing,
+		LastRun:   s.lastRun,
+		Moved:     atomic.LoadUint64(&s.moved),
+		LastError: s.lastErr,
+	}
+	s.mu.Unlock()
+
+	stats.Volumes = make([]VolumeUsage, len(s.volumes))
+	for i, volume := range s.volumes {
+		usage := VolumeUsage{Path: volume.path}
+		if totals != nil {
+			usage.Files, usage.Bytes, usage.Misplaced = totals[i].files, totals[i].bytes, totals[i].misplaced
+		}
+		if total, free, err := diskUsage(volume.path); err == nil {
+			usage.DiskTotal, usage.DiskFree = total, free
+		} else {
+			usage.DiskStatus = err.Error()
+		}
+		stats.Volumes[i] = usage
+	}
+	return stats
+}
+
+// lookup 先在文件所属的卷上执行 fn，文件不存在时依次尝试其他卷
+func (s *ShardedStorage) lookup(path string, fn func(volume *shardVolume) error) error {
+	key, err := shardKey(path)
+	if err != nil {
+		return err
+	}
+
+	owner := s.owner(key)
+	err = fn(s.volumes[owner])
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	for i, volume := range s.volumes {
+		if i == owner {
+			continue
+		}
+		if fn(volume) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// removeCopies 删除文件在其他卷上的副本
+func (s *ShardedStorage) removeCopies(path string, owner int) {
+	for i, volume := range s.volumes {
+		if i == owner || !volume.storage.Exists(path) {
+			continue
+		}
+		if err := volume.storage.Delete(path); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Warnf("remove stale copy of %s on %s failed: %v", path, volume.path, err)
+		}
+	}
+}
+
+// owner 返回路径所属的卷
+func (s *ShardedStorage) owner(key string) int {
+	hash := shardHash(key)
+	i := sort.Search(len(s.ring), func(i int) bool {
+		return s.ring[i].hash >= hash
+	})
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].volume
+}
+
+// shardKey 规范化路径作为哈希的键
+func shardKey(path string) (string, error) {
+	cleaned, err := CleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	if cleaned != "/" {
+		cleaned = strings.TrimSuffix(cleaned, "/")
+	}
+	return cleaned, nil
+}
+
+// shardKeyOf 用于遍历得到的路径，这些路径已经是规范的
+func shardKeyOf(path string) string {
+	key, err := shardKey(path)
+	if err != nil {
+		return path
+	}
+	return key
+}
+
+func shardHash(s string) uint32 {
+	sum := sha1.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestShardedStatsUsesCachedUsage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewShardedStorage([]string{filepath.Join(dir, "v1"), filepath.Join(dir, "v2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := WriteBytes(s, fmt.Sprintf("/com/ex/lib/%d/lib.jar", i), []byte("jar")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 尚未统计时只返回磁盘容量
+	stats := s.Stats()
+	if !stats.UsageTime.IsZero() || len(stats.Volumes) != 2 || stats.Volumes[0].Files != 0 {
+		t.Fatalf("Stats before refresh = %+v", stats)
+	}
+
+	s.RefreshUsage()
+	stats = s.Stats()
+	files, bytes := int64(0), int64(0)
+	for _, v := range stats.Volumes {
+		files += v.Files
+		bytes += v.Bytes
+		if v.Misplaced != 0 {
+			t.Errorf("volume %s has %d misplaced files", v.Path, v.Misplaced)
+		}
+	}
+	if stats.UsageTime.IsZero() || files != 20 || bytes != 60 {
+		t.Errorf("Stats after refresh: files %d bytes %d usageTime %v", files, bytes, stats.UsageTime)
+	}
+
+	// 写入后在下次刷新前返回缓存的统计
+	if err := WriteBytes(s, "/com/ex/lib/new/lib.jar", []byte("jar")); err != nil {
+		t.Fatal(err)
+	}
+	files = 0
+	for _, v := range s.Stats().Volumes {
+		files += v.Files
+	}
+	if files != 20 {
+		t.Errorf("Stats without refresh counted %d files, want cached 20", files)
+	}
+}
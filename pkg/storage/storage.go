@@ -1,24 +1,98 @@
-// pkg/storage/storage.go
 package storage
 
 import (
-	"net/http"
+	"bytes"
+	"errors"
+	"io"
+	"path"
 	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.New()
+
+func init() {
+	log.SetLevel(logrus.InfoLevel)
+	log.SetFormatter(&logrus.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+	})
+}
+
+var (
+	// ErrNotFound 文件或目录不存在
+	ErrNotFound = errors.New("file not found")
+
+	// ErrQuotaExceeded 超出仓库存储配额
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
 )
 
 // Storage 存储接口定义
 type Storage interface {
-	// Read 读取文件，返回数据、状态码、响应头和错误
-	Read(path string) ([]byte, int, http.Header, error)
+	// Read 打开文件用于流式读取，调用方负责关闭返回的 Content
+	Read(path string) (*Content, error)
 
-	// Write 写入文件
-	Write(path string, data []byte) error
+	// Write 从 reader 流式写入文件，size 为 -1 表示长度未知
+	Write(path string, r io.Reader, size int64) error
 
 	// List 列出目录内容
 	List(path string) ([]FileInfo, error)
 
 	// Exists 检查文件或目录是否存在
 	Exists(path string) bool
+
+	// Stat 返回单个文件或目录的元信息，不存在时返回 ErrNotFound
+	Stat(path string) (*FileInfo, error)
+
+	// Delete 删除文件或整个目录，不存在时返回 ErrNotFound
+	Delete(path string) error
+}
+
+// AccessTracker 记录客户端访问的存储，分层存储和缓存淘汰按访问时间选择迁移和淘汰的文件
+//
+// Read 本身不记录访问，完整性校验、索引重建、导出和迁移等后台任务的读取不影响访问时间，
+// 只有响应客户端请求后才通过 Touch 记录。包装器需要将 Touch 转发给底层存储
+type AccessTracker interface {
+	Touch(path string)
+}
+
+// Touch 记录客户端对文件的访问，存储不记录访问时忽略
+func Touch(s Storage, path string) {
+	if tracker, ok := s.(AccessTracker); ok {
+		tracker.Touch(path)
+	}
+}
+
+// SharedLocker 支持跨进程锁的存储。多个实例共享存储时，根据目录内容重新生成文件（如元数据）
+// 需要在锁内列出目录并写入，避免各实例基于不同的列表互相覆盖。包装器需要将 LockPath 转发给底层存储
+type SharedLocker interface {
+	LockPath(path string) (func(), error)
+}
+
+// LockPath 获取路径的跨进程锁，返回解锁函数，存储不支持或未启用锁文件时直接返回
+func LockPath(s Storage, path string) (func(), error) {
+	if locker, ok := s.(SharedLocker); ok {
+		return locker.LockPath(path)
+	}
+	return func() {}, nil
+}
+
+// Content 可流式读取的文件内容
+type Content struct {
+	io.ReadCloser
+	Size        int64     // 内容长度（字节），未知时为 -1
+	ContentType string    // MIME 类型
+	ModTime     time.Time // 最后修改时间，未知时为零值
+}
+
+// NewContent 使用内存数据创建 Content
+func NewContent(path string, data []byte) *Content {
+	return &Content{
+		ReadCloser:  io.NopCloser(bytes.NewReader(data)),
+		Size:        int64(len(data)),
+		ContentType: getContentType(path),
+	}
 }
 
 // FileInfo 文件或目录的元信息
@@ -27,4 +101,50 @@ type FileInfo struct {
 	Size    int64     // 文件大小（字节），目录为 0
 	ModTime time.Time // 最后修改时间
 	IsDir   bool      // 是否为目录
+
+	// 以下字段仅由 Stat 返回，摘要来自写入时记录的属性，未知时为空
+	ContentType string // MIME 类型
+	SHA1        string // SHA-1 十六进制摘要
+	SHA256      string // SHA-256 十六进制摘要
+}
+
+// ReadBytes 读取整个文件，仅用于 maven-metadata.xml、校验和等小文件
+func ReadBytes(s Storage, path string) ([]byte, error) {
+	content, err := s.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return io.ReadAll(content)
+}
+
+// WriteBytes 写入内存中的数据
+func WriteBytes(s Storage, path string, data []byte) error {
+	return s.Write(path, bytes.NewReader(data), int64(len(data)))
+}
+
+// WalkFunc 遍历时对每个文件调用的函数，p 为相对于存储根的路径
+type WalkFunc func(p string, info FileInfo) error
+
+// Walk 递归遍历目录下的所有文件，不包含目录本身
+func Walk(s Storage, root string, fn WalkFunc) error {
+	entries, err := s.List(root)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		p := path.Join(root, entry.Name)
+		if entry.IsDir {
+			if err := Walk(s, p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(p, entry); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,370 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// promoteQueueSize 等待提升的文件数上限，队列已满时放弃本次提升，下次访问时重试
+const promoteQueueSize = 256
+
+// TieredStorage 冷热分层存储，新文件写入热层，长时间未访问的文件由后台迁移到冷层
+// 读取时先查热层再查冷层，开启提升时冷层文件被客户端访问（Touch）后由后台移回热层，
+// 后台任务的读取既不更新访问时间也不提升
+//
+// 访问时间只记录在内存中，重启后以文件修改时间为准
+type TieredStorage struct {
+	hot  Storage
+	cold Storage
+	opts TieredOptions
+
+	locks *pathLocker // 写入、删除和迁移同一路径时互斥
+
+	mu         sync.Mutex
+	lastAccess map[string]time.Time
+	promoting  map[string]bool // 已在队列中或正在提升的文件
+
+	promoteQueue chan string
+
+	migrated uint64
+	promoted uint64
+	stopChan chan struct{}
+}
+
+// TieredOptions 分层存储选项
+type TieredOptions struct {
+	MigrateAfter time.Duration // 超过该时长未访问的文件迁移到冷层
+	Interval     time.Duration // 后台迁移周期
+	Promote      bool          // 客户端访问冷层文件时移回热层
+}
+
+// TieredStats 分层存储统计信息
+type TieredStats struct {
+	Migrated uint64 `json:"migrated"`
+	Promoted uint64 `json:"promoted"`
+}
+
+// NewTieredStorage 创建冷热分层存储
+func NewTieredStorage(hot, cold Storage, opts TieredOptions) *TieredStorage {
+	return &TieredStorage{
+		hot:        hot,
+		cold:       cold,
+		opts:       opts,
+		locks:      newPathLocker(),
+		lastAccess: make(map[string]time.Time),
+		promoting:  make(map[string]bool),
+		stopChan:   make(chan struct{}),
+
+		promoteQueue: make(chan string, promoteQueueSize),
+	}
+}
+
+func (s *TieredStorage) Read(path string) (*Content, error) {
+	content, err := s.hot.Read(path)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return content, err
+	}
+	return s.cold.Read(path)
+}
+
+// Touch 记录客户端访问，开启提升时将只在冷层的文件加入后台提升队列，不阻塞调用方
+func (s *TieredStorage) Touch(path string) {
+	key := tierKey(path)
+	if s.hot.Exists(key) {
+		s.touch(key)
+		return
+	}
+	if !s.opts.Promote {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promoting[key] {
+		return
+	}
+	select {
+	case s.promoteQueue <- key:
+		s.promoting[key] = true
+	default:
+	}
+}
+
+// LockPath 在热层加锁，两层共享时每层各自的锁文件相互独立
+func (s *TieredStorage) LockPath(path string) (func(), error) {
+	return LockPath(s.hot, path)
+}
+
+func (s *TieredStorage) Write(path string, r io.Reader, size int64) error {
+	key := tierKey(path)
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	if err := s.hot.Write(path, r, size); err != nil {
+		return err
+	}
+	s.touch(key)
+
+	// 冷层中的旧版本已经过时
+	if err := s.cold.Delete(path); err != nil && !errors.Is(err, ErrNotFound) {
+		log.Warnf("delete stale %s from cold tier failed: %v", key, err)
+	}
+	return nil
+}
+
+// List 合并两层的目录内容，同名文件以热层为准
+func (s *TieredStorage) List(path string) ([]FileInfo, error) {
+	hotEntries, hotErr := s.hot.List(path)
+	coldEntries, coldErr := s.cold.List(path)
+	if hotErr != nil && coldErr != nil {
+		return nil, hotErr
+	}
+
+	merged := make(map[string]FileInfo, len(hotEntries)+len(coldEntries))
+	for _, entry := range coldEntries {
+		merged[entry.Name] = entry
+	}
+	for _, entry := range hotEntries {
+		merged[entry.Name] = entry
+	}
+
+	fileInfos := make([]FileInfo, 0, len(merged))
+	for _, entry := range merged {
+		fileInfos = append(fileInfos, entry)
+	}
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].Name < fileInfos[j].Name
+	})
+	return fileInfos, nil
+}
+
+func (s *TieredStorage) Exists(path string) bool {
+	return s.hot.Exists(path) || s.cold.Exists(path)
+}
+
+func (s *TieredStorage) Stat(path string) (*FileInfo, error) {
+	info, err := s.hot.Stat(path)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	return s.cold.Stat(path)
+}
+
+// Delete 从两层中删除，两层都不存在时返回 ErrNotFound
+func (s *TieredStorage) Delete(path string) error {
+	key := tierKey(path)
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	hotErr := s.hot.Delete(path)
+	if hotErr != nil && !errors.Is(hotErr, ErrNotFound) {
+		return hotErr
+	}
+	coldErr := s.cold.Delete(path)
+	if coldErr != nil && !errors.Is(coldErr, ErrNotFound) {
+		return coldErr
+	}
+	if hotErr != nil && coldErr != nil {
+		return hotErr
+	}
+
+	s.forget(key)
+	return nil
+}
+
+// Start 启动后台提升，并按周期在后台迁移文件
+func (s *TieredStorage) Start() {
+	if s.opts.Promote {
+		go s.promoteLoop()
+	}
+	if s.opts.Interval <= 0 || s.opts.MigrateAfter <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				migrated, err := s.Migrate()
+				if err != nil {
+					log.Warnf("migrate to cold tier failed: %v", err)
+				} else if migrated > 0 {
+					log.Infof("migrated %d files to cold tier", migrated)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台迁移和提升
+func (s *TieredStorage) Stop() {
+	close(s.stopChan)
+}
+
+// Migrate 将超过 MigrateAfter 未访问的文件从热层迁移到冷层，返回迁移的文件数
+func (s *TieredStorage) Migrate() (int, error) {
+	cutoff := time.Now().Add(-s.opts.MigrateAfter)
+
+	candidates := []string{}
+	err := Walk(s.hot, "/", func(p string, info FileInfo) error {
+		if s.accessTime(p, info.ModTime).Before(cutoff) {
+			candidates = append(candidates, p)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || !s.hot.Exists("/") {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	migrated := 0
+	for _, p := range candidates {
+		ok, err := s.demote(p, cutoff)
+		if err != nil {
+			log.Warnf("migrate %s to cold tier failed: %v", p, err)
+			continue
+		}
+		if ok {
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+// Stats 返回迁移和提升的文件数
+func (s *TieredStorage) Stats() TieredStats {
+	return TieredStats{
+		Migrated: atomic.LoadUint64(&s.migrated),
+		Promoted: atomic.LoadUint64(&s.promoted),
+	}
+}
+
+// demote 将文件移到冷层，持锁后重新检查访问时间，期间被访问或修改过时放弃
+func (s *TieredStorage) demote(key string, cutoff time.Time) (bool, error) {
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	info, err := s.hot.Stat(key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if info.IsDir || !s.accessTime(key, info.ModTime).Before(cutoff) {
+		return false, nil
+	}
+
+	// 先写入冷层再删除热层，迁移过程中读取总能在某一层找到文件
+	if err := moveFile(s.hot, s.cold, key); err != nil {
+		return false, err
+	}
+	s.forget(key)
+	atomic.AddUint64(&s.migrated, 1)
+	return true, nil
+}
+
+// promoteLoop 依次处理提升队列中的文件
+func (s *TieredStorage) promoteLoop() {
+	for {
+		select {
+		case key := <-s.promoteQueue:
+			if err := s.promote(key); err != nil {
+				if !errors.Is(err, ErrNotFound) {
+					log.Warnf("promote %s to hot tier failed: %v", key, err)
+				}
+			} else {
+				s.touch(key)
+			}
+
+			s.mu.Lock()
+			delete(s.promoting, key)
+			s.mu.Unlock()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// promote 将冷层文件移回热层
+func (s *TieredStorage) promote(key string) error {
+	unlock := s.locks.Lock(key)
+	defer unlock()
+
+	// 等待锁期间可能已被其他请求提升或重新写入
+	if s.hot.Exists(key) {
+		return nil
+	}
+	if err := moveFile(s.cold, s.hot, key); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.promoted, 1)
+	return nil
+}
+
+// moveFile 将文件从 src 复制到 dst 后从 src 删除
+func moveFile(src, dst Storage, p string) error {
+	content, err := src.Read(p)
+	if err != nil {
+		return err
+	}
+	err = dst.Write(p, content, content.Size)
+	content.Close()
+	if err != nil {
+		return err
+	}
+	return src.Delete(p)
+}
+
+// touch 记录访问时间
+func (s *TieredStorage) touch(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAccess[key] = time.Now()
+}
+
+// forget 删除访问记录，删除目录时一并删除目录下的记录
+func (s *TieredStorage) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lastAccess, key)
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	for p := range s.lastAccess {
+		if strings.HasPrefix(p, prefix) {
+			delete(s.lastAccess, p)
+		}
+	}
+}
+
+// accessTime 返回最后访问时间，没有访问记录时使用修改时间
+func (s *TieredStorage) accessTime(key string, modTime time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.lastAccess[key]; ok && t.After(modTime) {
+		return t
+	}
+	return modTime
+}
+
+// tierKey 规范化路径用于加锁和记录访问时间，路径不合法时原样返回，由底层存储拒绝
+func tierKey(p string) string {
+	cleaned, err := CleanPath(p)
+	if err != nil {
+		return p
+	}
+	return strings.TrimSuffix(cleaned, "/")
+}
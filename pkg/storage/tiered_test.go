@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTieredStorageOnlyTouchPromotes(t *testing.T) {
+	hot := NewFileSystemStorage(t.TempDir())
+	cold := NewFileSystemStorage(t.TempDir())
+	tiered := NewTieredStorage(hot, cold, TieredOptions{MigrateAfter: time.Hour, Promote: true})
+	cached := NewMemoryCacheStorage(tiered, 1<<20, 1<<10)
+	tiered.Start()
+	defer tiered.Stop()
+
+	const p = "/com/ex/lib/1.0/lib-1.0.jar"
+	if err := WriteBytes(cold, p, []byte("jar")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后台任务的读取不把冷层文件移回热层
+	if data, err := ReadBytes(cached, p); err != nil || string(data) != "jar" {
+		t.Fatalf("Read = %q, %v", data, err)
+	}
+	if hot.Exists(p) || tiered.Stats().Promoted != 0 {
+		t.Fatal("Read promoted file to hot tier")
+	}
+
+	// 客户端访问经过内存缓存同样转发到分层存储，提升在后台完成
+	Touch(cached, p)
+	Touch(cached, p)
+	deadline := time.Now().Add(5 * time.Second)
+	for tiered.Stats().Promoted == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !hot.Exists(p) || cold.Exists(p) || tiered.Stats().Promoted != 1 {
+		t.Fatalf("Touch did not promote: hot=%v cold=%v", hot.Exists(p), cold.Exists(p))
+	}
+
+	// 刚访问过的文件不会被迁移
+	if migrated, err := tiered.Migrate(); err != nil || migrated != 0 {
+		t.Errorf("Migrate = %d, %v", migrated, err)
+	}
+}